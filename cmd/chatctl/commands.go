@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	creds "grpc-streaming/internal/client/tls"
+	"grpc-streaming/internal/config"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+	pb "grpc-streaming/streaming/grpc"
+)
+
+type ctl struct {
+	admin  pb.AdminClient
+	cfg    *config.Ctl
+	out    *printer
+	stdout io.Writer
+}
+
+func (c *ctl) run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: command is required", errUsage)
+	}
+
+	command, args := args[0], args[1:]
+	if command == "streams" || command == "rooms" || command == "history" || command == "certs" {
+		if len(args) == 0 {
+			return fmt.Errorf("%w: %s needs a subcommand", errUsage, command)
+		}
+		command, args = command+" "+args[0], args[1:]
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+
+	switch command {
+	case "streams list":
+		return c.streamsList(ctx, args)
+	case "streams kill":
+		return c.streamsKill(ctx, args)
+	case "rooms list":
+		return c.roomsList(ctx, args)
+	case "history export":
+		return c.historyExport(ctx, args)
+	case "broadcast":
+		return c.broadcast(ctx, args)
+	case "log-level":
+		return c.logLevel(ctx, args)
+	case "certs status":
+		return c.certsStatus(ctx, args)
+	}
+
+	return fmt.Errorf("%w: unknown command %q", errUsage, command)
+}
+
+// parse parses the command flags and checks the number of positional args.
+func parse(fs *flag.FlagSet, args []string, positional int) ([]string, error) {
+	fs.SetOutput(io.Discard)
+	if err := fs.Parse(args); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", errUsage, fs.Name(), err)
+	}
+	if fs.NArg() != positional {
+		return nil, fmt.Errorf("%w: %s expects %d argument(s)", errUsage, fs.Name(), positional)
+	}
+
+	return fs.Args(), nil
+}
+
+func (c *ctl) streamsList(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("streams list", flag.ContinueOnError)
+	room := fs.String("room", "", "only streams of this room")
+	if _, err := parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	resp, err := c.admin.ListStreams(ctx, &pb.ListStreamsRequest{Room: *room})
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(resp.Streams))
+	for _, s := range resp.Streams {
+		rows = append(rows, []string{
+			s.Id,
+			s.Principal,
+			s.Peer,
+			s.Room,
+			s.Uptime.AsDuration().String(),
+			strconv.Itoa(int(s.QueueDepth)),
+			strconv.FormatUint(s.Dropped, 10),
+		})
+	}
+
+	return c.out.print(resp, []string{"ID", "PRINCIPAL", "PEER", "ROOM", "UPTIME", "QUEUE", "DROPPED"}, rows)
+}
+
+func (c *ctl) streamsKill(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("streams kill", flag.ContinueOnError)
+	reason := fs.String("reason", "", "reason sent to the disconnected client")
+	args, err := parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.admin.KillStream(ctx, &pb.KillStreamRequest{Id: args[0], Reason: *reason})
+	if err != nil {
+		return err
+	}
+
+	return c.out.print(resp, []string{"KILLED"}, [][]string{{args[0]}})
+}
+
+func (c *ctl) roomsList(ctx context.Context, args []string) error {
+	if _, err := parse(flag.NewFlagSet("rooms list", flag.ContinueOnError), args, 0); err != nil {
+		return err
+	}
+
+	resp, err := c.admin.ListRooms(ctx, &pb.ListRoomsRequest{})
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(resp.Rooms))
+	for _, room := range resp.Rooms {
+		rows = append(rows, []string{room.Name, strconv.Itoa(int(room.Subscribers)), strconv.Itoa(int(room.History))})
+	}
+
+	return c.out.print(resp, []string{"ROOM", "SUBSCRIBERS", "HISTORY"}, rows)
+}
+
+// historyExport streams the entries as they arrive, JSON output is one
+// entry per line so exports can be piped into other tools.
+func (c *ctl) historyExport(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("history export", flag.ContinueOnError)
+	since := fs.Duration("since", 0, "only messages newer than this, 0 exports the whole history")
+	args, err := parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	req := &pb.ExportHistoryRequest{Room: args[0]}
+	if *since > 0 {
+		req.Since = timestamppb.New(time.Now().Add(-*since))
+	}
+
+	stream, err := c.admin.ExportHistory(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	var t *table
+	if c.cfg.Output == "table" {
+		t = c.out.table([]string{"TIME", "PRINCIPAL", "TYPE", "BODY"})
+	}
+
+	for {
+		entry, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if t == nil {
+			if err = c.out.jsonLine(entry); err != nil {
+				return err
+			}
+			continue
+		}
+		t.row(formatTime(entry.Time.AsTime()), entry.Principal, strings.ToLower(entry.Type.String()), entry.Body)
+	}
+
+	if t != nil {
+		return t.flush()
+	}
+
+	return nil
+}
+
+func (c *ctl) broadcast(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("broadcast", flag.ContinueOnError)
+	room := fs.String("room", "", "target room, every room when empty")
+	fs.SetOutput(io.Discard)
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: broadcast: %v", errUsage, err)
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("%w: broadcast expects a message", errUsage)
+	}
+
+	resp, err := c.admin.Broadcast(ctx, &pb.BroadcastRequest{Room: *room, Body: strings.Join(fs.Args(), " ")})
+	if err != nil {
+		return err
+	}
+
+	return c.out.print(resp, []string{"RECIPIENTS"}, [][]string{{strconv.Itoa(int(resp.Recipients))}})
+}
+
+func (c *ctl) logLevel(ctx context.Context, args []string) error {
+	args, err := parse(flag.NewFlagSet("log-level", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.admin.SetLogLevel(ctx, &pb.SetLogLevelRequest{Level: args[0]})
+	if err != nil {
+		return err
+	}
+
+	return c.out.print(resp, []string{"PREVIOUS", "LEVEL"}, [][]string{{resp.PreviousLevel, strings.ToLower(args[0])}})
+}
+
+// certsStatus combines the certificates reported by the server with the
+// local ones chatctl connects with, local names are prefixed with "local/".
+func (c *ctl) certsStatus(ctx context.Context, args []string) error {
+	if _, err := parse(flag.NewFlagSet("certs status", flag.ContinueOnError), args, 0); err != nil {
+		return err
+	}
+
+	resp, err := c.admin.CertificateStatus(ctx, &pb.CertificateStatusRequest{})
+	if err != nil {
+		return err
+	}
+
+	if c.cfg.TLS.Enabled {
+		local, err := creds.LoadCertificates(c.cfg.TLS.Mutual)
+		if err != nil {
+			return fmt.Errorf("cannot load local certificates: %w", err)
+		}
+
+		for _, cert := range local {
+			resp.Certificates = append(resp.Certificates, &pb.CertificateInfo{
+				Name:      "local/" + cert.Name,
+				Subject:   cert.Subject.String(),
+				Issuer:    cert.Issuer.String(),
+				DnsNames:  cert.DNSNames,
+				NotBefore: timestamppb.New(cert.NotBefore),
+				NotAfter:  timestamppb.New(cert.NotAfter),
+			})
+		}
+	}
+
+	if c.cfg.Output == "table" {
+		fmt.Fprintf(c.stdout, "server TLS: %t, mutual TLS: %t\n", resp.TlsEnabled, resp.MutualTls)
+	}
+
+	rows := make([][]string, 0, len(resp.Certificates))
+	for _, cert := range resp.Certificates {
+		rows = append(rows, []string{
+			cert.Name,
+			cert.Subject,
+			cert.Issuer,
+			formatTime(cert.NotAfter.AsTime()),
+			formatUntil(cert.NotAfter.AsTime()),
+		})
+	}
+
+	return c.out.print(resp, []string{"NAME", "SUBJECT", "ISSUER", "NOT AFTER", "EXPIRES IN"}, rows)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"grpc-streaming/internal/client/interceptors"
+	creds "grpc-streaming/internal/client/tls"
+	"grpc-streaming/internal/config"
+	"grpc-streaming/internal/logging"
+	"log/slog"
+	"os"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	pb "grpc-streaming/streaming/grpc"
+)
+
+const usage = `usage: chatctl [flags] <command> [command flags] [args]
+
+commands:
+  streams list [-room room]          list connected streams
+  streams kill [-reason text] <id>   disconnect a stream
+  rooms list                         list rooms with subscribers and history
+  history export [-since 1h] <room>  export the in-memory history of a room
+  broadcast [-room room] <message>   send a system message, to every room by default
+  log-level <level>                  change the server log level
+  certs status                       show server and local certificates
+
+flags:
+`
+
+// errUsage reports a malformed command line, the usage is printed for it.
+var errUsage = errors.New("invalid command line")
+
+func main() {
+	cfg := config.DefaultCtl()
+	loader := config.NewLoader("chatctl", config.CtlEnvPrefix, cfg)
+	loader.SetUsage(usage)
+	opts, err := loader.Load(os.Args[1:])
+	if err == nil && opts.PrintConfig {
+		err = loader.PrintConfig(os.Stdout)
+	}
+	if err == nil && opts.PrintSchema {
+		err = loader.PrintSchema(os.Stdout)
+	}
+	if err != nil {
+		slog.With("error", err).Error("invalid configuration")
+		os.Exit(1)
+	}
+	if opts.PrintConfig || opts.PrintSchema {
+		return
+	}
+
+	logger, err := logging.New(cfg.Logging.Config())
+	if err != nil {
+		slog.With("error", err).Error("cannot configure logging")
+		os.Exit(1)
+	}
+	defer logger.Close()
+	slog.SetDefault(logger.Logger)
+
+	conn, err := dial(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "chatctl:", err)
+		os.Exit(1)
+	}
+	defer conn.Close()
+
+	ctl := &ctl{
+		admin:  pb.NewAdminClient(conn),
+		cfg:    cfg,
+		out:    newPrinter(os.Stdout, cfg.Output),
+		stdout: os.Stdout,
+	}
+
+	err = ctl.run(context.Background(), loader.Args())
+	switch {
+	case errors.Is(err, errUsage):
+		fmt.Fprintln(os.Stderr, "chatctl:", err)
+		fmt.Fprintln(os.Stderr, "run 'chatctl -h' for usage")
+		os.Exit(2)
+	case err != nil:
+		if s, ok := status.FromError(err); ok {
+			fmt.Fprintf(os.Stderr, "chatctl: %s: %s\n", s.Code(), s.Message())
+		} else {
+			fmt.Fprintln(os.Stderr, "chatctl:", err)
+		}
+		os.Exit(1)
+	}
+}
+
+func dial(cfg *config.Ctl) (*grpc.ClientConn, error) {
+	auth := interceptors.NewAuthClientInterceptor(cfg.Token)
+	requestID := interceptors.NewRequestIDClientInterceptor()
+	options := []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(requestID.Unary(), auth.Unary()),
+		grpc.WithChainStreamInterceptor(requestID.Stream(), auth.Stream()),
+	}
+
+	if cfg.TLS.Enabled {
+		tlsCredentials, err := creds.LoadClientTLSCredentials(cfg.TLS.Mutual)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load client TLS credentials: %w", err)
+		}
+
+		options = append(options, grpc.WithTransportCredentials(tlsCredentials))
+	}
+
+	return grpc.NewClient(cfg.Address, options...)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// printer renders command results as an aligned table or as JSON.
+type printer struct {
+	w      io.Writer
+	format string
+}
+
+func newPrinter(w io.Writer, format string) *printer {
+	return &printer{w: w, format: format}
+}
+
+// print writes msg as indented JSON, or the header and rows as a table.
+func (p *printer) print(msg proto.Message, header []string, rows [][]string) error {
+	if p.format == "json" {
+		data, err := protojson.MarshalOptions{Multiline: true, Indent: "  ", EmitUnpopulated: true}.Marshal(msg)
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintln(p.w, string(data))
+		return err
+	}
+
+	table := p.table(header)
+	for _, row := range rows {
+		table.row(row...)
+	}
+
+	return table.flush()
+}
+
+// table is a tabwriter writing one row per call, used for streamed results.
+type table struct {
+	tw *tabwriter.Writer
+}
+
+func (p *printer) table(header []string) *table {
+	t := &table{tw: tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)}
+	t.row(header...)
+
+	return t
+}
+
+func (t *table) row(cells ...string) {
+	fmt.Fprintln(t.tw, strings.Join(cells, "\t"))
+}
+
+func (t *table) flush() error {
+	return t.tw.Flush()
+}
+
+// jsonLine writes msg as a single JSON line, used for streamed results.
+func (p *printer) jsonLine(msg proto.Message) error {
+	data, err := protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(p.w, string(data))
+	return err
+}
+
+func formatTime(t time.Time) string {
+	return t.Local().Format(time.DateTime)
+}
+
+// formatUntil renders the time left until t, negative when t is in the past.
+func formatUntil(t time.Time) string {
+	left := time.Until(t)
+	if left >= 48*time.Hour || left <= -48*time.Hour {
+		return fmt.Sprintf("%dd", int(left.Hours()/24))
+	}
+
+	return left.Round(time.Minute).String()
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"grpc-streaming/pkg/client"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+	pb "grpc-streaming/streaming/grpc"
+)
+
+// rpcTimeout bounds the unary calls made on behalf of slash commands.
+const rpcTimeout = 5 * time.Second
+
+const commandHelp = `commands:
+  /join <room>     leave the current room and join another one
+  /leave           leave the current room
+  /who [room]      list the participants of a room
+  /history [n]     show the last n messages of the current room
+  /feed [k=v ...]  show the messages of the server's feed, k=v are passed to it
+  /quit            leave and exit
+anything else is sent to the current room`
+
+// output is where the interactive front ends render the client.
+type output interface {
+	// notice shows client side feedback, not a chat message.
+	notice(format string, args ...any)
+	message(msg *pb.Message)
+}
+
+// execute runs a single input line and reports whether the user wants to quit.
+func execute(c *client.Client, line string, out output) bool {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return false
+	}
+
+	if !strings.HasPrefix(line, "/") {
+		if err := c.Send(line); errors.Is(err, client.ErrNotInRoom) {
+			out.notice("not in a room, /join one first")
+		} else if err != nil {
+			out.notice("cannot send: %s", describe(err))
+		}
+		return false
+	}
+
+	command, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+
+	switch command {
+	case "/join":
+		if arg == "" {
+			out.notice("usage: /join <room>")
+			return false
+		}
+		if err := c.Join(arg); err != nil {
+			out.notice("cannot join %s: %s", arg, describe(err))
+		}
+	case "/leave":
+		if c.Room() == "" {
+			out.notice("not in a room")
+			return false
+		}
+		c.Leave()
+	case "/who":
+		who(c, arg, out)
+	case "/history":
+		history(c, arg, out)
+	case "/feed":
+		fetchFeed(c, arg, out)
+	case "/quit":
+		return true
+	case "/help":
+		for _, line := range strings.Split(commandHelp, "\n") {
+			out.notice("%s", line)
+		}
+	default:
+		out.notice("unknown command %s, /help lists the commands", command)
+	}
+
+	return false
+}
+
+func who(c *client.Client, room string, out output) {
+	if room == "" {
+		room = c.Room()
+	}
+	if room == "" {
+		out.notice("usage: /who <room>")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rpcTimeout)
+	defer cancel()
+
+	principals, err := c.Who(ctx, room)
+	if err != nil {
+		out.notice("/who failed: %s", describe(err))
+		return
+	}
+
+	if len(principals) == 0 {
+		out.notice("nobody is in %s", room)
+		return
+	}
+	out.notice("in %s: %s", room, strings.Join(principals, ", "))
+}
+
+func history(c *client.Client, arg string, out output) {
+	var limit int
+	if arg != "" {
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 1 {
+			out.notice("usage: /history [n]")
+			return
+		}
+		limit = n
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rpcTimeout)
+	defer cancel()
+
+	messages, err := c.History(ctx, limit)
+	if err != nil {
+		out.notice("/history failed: %s", describe(err))
+		return
+	}
+
+	for _, msg := range messages {
+		out.message(msg)
+	}
+}
+
+func fetchFeed(c *client.Client, arg string, out output) {
+	params := make(map[string]string)
+	for _, pair := range strings.Fields(arg) {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			out.notice("usage: /feed [key=value ...]")
+			return
+		}
+		params[key] = value
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rpcTimeout)
+	defer cancel()
+
+	n := 0
+	err := c.Feed(ctx, c.Room(), params, 0, func(msg *pb.Message) error {
+		n++
+		out.message(msg)
+		return nil
+	})
+	if err != nil {
+		out.notice("/feed failed after %d messages: %s", n, describe(err))
+		return
+	}
+	if n == 0 {
+		out.notice("the feed is empty")
+	}
+}
+
+// describeEvent renders a client event as a notice.
+func describeEvent(ev client.Event) string {
+	switch ev.State {
+	case client.StateJoined:
+		return "joined " + ev.Room
+	case client.StateLeft:
+		return "left " + ev.Room
+	case client.StateReconnecting:
+		return fmt.Sprintf("lost %s (%s), rejoining in %s", ev.Room, describe(ev.Err), ev.Retry)
+	default:
+		return fmt.Sprintf("disconnected from %s: %s", ev.Room, describe(ev.Err))
+	}
+}
+
+// formatMessage renders a chat or system message as a single line.
+func formatMessage(msg *pb.Message) string {
+	at := time.Now()
+	if msg.SentAt != nil {
+		at = msg.SentAt.AsTime()
+	}
+
+	if msg.Type == pb.Message_SYSTEM {
+		return fmt.Sprintf("[%s] *** %s", at.Local().Format(time.TimeOnly), msg.Body)
+	}
+	if st := client.MessageStatus(msg); st != nil {
+		for _, detail := range st.Details() {
+			if info, ok := detail.(*errdetails.ErrorInfo); ok {
+				return fmt.Sprintf("[%s] *** not delivered: %s (%s)", at.Local().Format(time.TimeOnly), st.Message(), info.Reason)
+			}
+		}
+		return fmt.Sprintf("[%s] *** not delivered: %s", at.Local().Format(time.TimeOnly), st.Message())
+	}
+
+	return fmt.Sprintf("[%s] %s: %s", at.Local().Format(time.TimeOnly), msg.Sender, msg.Body)
+}
+
+// describe renders gRPC errors without the "rpc error: code = ..." noise.
+func describe(err error) string {
+	if err == nil {
+		return "closed by the server"
+	}
+	if s, ok := status.FromError(err); ok {
+		return s.Message()
+	}
+
+	return err.Error()
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"grpc-streaming/internal/config"
+	"grpc-streaming/pkg/client"
+	"log/slog"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+	pb "grpc-streaming/streaming/grpc"
+)
+
+// runGenerate joins the configured room and sends a fake message every second
+// until ctx is canceled, incoming messages are only logged.
+func runGenerate(ctx context.Context, c *client.Client, cfg *config.Client, logger *slog.Logger) error {
+	if err := c.Join(cfg.Room); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Debug("shutting down...")
+			c.Leave()
+			return nil
+		case in := <-c.Messages():
+			if in.Type == pb.Message_SYSTEM {
+				logger.With("body", in.Body, "sender", in.Sender).Warn("system message")
+				continue
+			}
+			if st := client.MessageStatus(in); st != nil {
+				logger.With("code", st.Code(), "error", st.Message()).Warn("message not delivered")
+				continue
+			}
+			logger.With("body", in.Body, "sender", in.Sender).Debug("got server message")
+		case ev := <-c.Events():
+			switch ev.State {
+			case client.StateReconnecting:
+				logger.With("room", ev.Room, "error", ev.Err, "retry", ev.Retry).Warn("chat stream lost, rejoining")
+			case client.StateDisconnected:
+				return ev.Err
+			default:
+				logger.With("room", ev.Room).Debug(describeEvent(ev))
+			}
+		case <-ticker.C:
+			msg := gofakeit.Name() + " want to drink " + gofakeit.BeerName()
+			if err := c.Send(msg); err != nil {
+				logger.With(slog.String("error", err.Error())).Error("Failed to send a message")
+			}
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"grpc-streaming/internal/config"
+	"grpc-streaming/pkg/client"
+	"io"
+	"sync"
+
+	pb "grpc-streaming/streaming/grpc"
+)
+
+// console is the line oriented output, it serializes writes of the stream
+// goroutines and the command loop.
+type console struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (c *console) notice(format string, args ...any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fmt.Fprintf(c.w, "*** "+format+"\n", args...)
+}
+
+func (c *console) message(msg *pb.Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fmt.Fprintln(c.w, formatMessage(msg))
+}
+
+// runInteractive reads chat lines and slash commands from in until /quit,
+// end of input or ctx cancellation.
+func runInteractive(ctx context.Context, c *client.Client, cfg *config.Client, in io.Reader, out io.Writer) error {
+	term := &console{w: out}
+	defer func() {
+		c.Leave()
+		// Показываем события, накопившиеся до выхода, в том числе "left"
+		for {
+			select {
+			case ev := <-c.Events():
+				term.notice("%s", describeEvent(ev))
+			default:
+				return
+			}
+		}
+	}()
+
+	if cfg.Room != "" {
+		if err := c.Join(cfg.Room); err != nil {
+			term.notice("cannot join %s: %s", cfg.Room, describe(err))
+		}
+	}
+
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg := <-c.Messages():
+			term.message(msg)
+		case ev := <-c.Events():
+			term.notice("%s", describeEvent(ev))
+		case line, ok := <-lines:
+			if !ok || execute(c, line, term) {
+				return nil
+			}
+		}
+	}
+}
@@ -2,15 +2,13 @@ package main
 
 import (
 	"context"
-	"errors"
-	"flag"
-	"github.com/brianvoe/gofakeit/v7"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/credentials/insecure"
-	"google.golang.org/grpc/status"
-	"grpc-streaming/internal/client/interceptors"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	creds "grpc-streaming/internal/client/tls"
-	"io"
+	"grpc-streaming/internal/config"
+	"grpc-streaming/internal/logging"
+	"grpc-streaming/internal/tracing"
+	"grpc-streaming/pkg/client"
 	"log/slog"
 	"os"
 	"os/signal"
@@ -18,120 +16,125 @@ import (
 	"time"
 
 	"google.golang.org/grpc"
-	pb "grpc-streaming/streaming/grpc"
 )
 
 func main() {
-	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
-	slog.SetDefault(logger)
+	cfg := config.DefaultClient()
+	loader := config.NewLoader("client", config.ClientEnvPrefix, cfg)
+	opts, err := loader.Load(os.Args[1:])
+	if err == nil && opts.PrintConfig {
+		err = loader.PrintConfig(os.Stdout)
+	}
+	if err == nil && opts.PrintSchema {
+		err = loader.PrintSchema(os.Stdout)
+	}
+	if err != nil {
+		slog.With("error", err).Error("invalid configuration")
+		os.Exit(1)
+	}
+	if opts.PrintConfig || opts.PrintSchema {
+		return
+	}
 
-	var address string
-	var enableTLS, mutualTLS bool
+	if cfg.Mode == "tui" && (cfg.Logging.Output == "stdout" || cfg.Logging.Output == "stderr") {
+		// Логи в терминал испортили бы экран TUI
+		cfg.Logging.Output = "none"
+	}
+	if cfg.Mode == "interactive" && cfg.Logging.Output == "stdout" {
+		// stdout занят чатом, логи идут в stderr
+		cfg.Logging.Output = "stderr"
+	}
 
-	flag.StringVar(&address, "address", "", "the server address")
-	flag.BoolVar(&enableTLS, "tls", false, "enable SSL/TLS")
-	flag.BoolVar(&mutualTLS, "mutualTLS", false, "enable mutual TLS")
-	flag.Parse()
+	logger, err := logging.New(cfg.Logging.Config())
+	if err != nil {
+		slog.With("error", err).Error("cannot configure logging")
+		os.Exit(1)
+	}
+	defer logger.Close()
+	slog.SetDefault(logger.Logger)
+
+	address, room := cfg.Address, cfg.Room
 
-	logger.With("address", address, "TLS", enableTLS, "mutualTLS", mutualTLS).Info("connecting to server...")
+	logger.With("address", address, "TLS", cfg.TLS.Enabled, "mutualTLS", cfg.TLS.Mutual, "room", room).Info("connecting to server...")
 
-	parentCtx, cancel := context.WithCancel(context.Background())
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing.Config("chat-client"))
+	if err != nil {
+		logger.With("error", err).Error("cannot set up tracing")
+		os.Exit(1)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			logger.With("error", err).Error("failed to flush traces")
+		}
+	}()
 
-	interceptor := interceptors.NewAuthClientInterceptor()
-	clientOptions := []grpc.DialOption{
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithUnaryInterceptor(interceptor.Unary()),
-		grpc.WithStreamInterceptor(interceptor.Stream()),
+	clientOptions := []client.Option{
+		client.WithToken(cfg.Token),
+		client.WithKeepalive(cfg.Keepalive.Params()),
+		client.WithHeartbeat(cfg.Keepalive.HeartbeatInterval, cfg.Keepalive.IdleTimeout),
+		client.WithLogger(logger.Logger),
+		client.WithDialOptions(grpc.WithStatsHandler(otelgrpc.NewClientHandler(
+			otelgrpc.WithMessageEvents(otelgrpc.ReceivedEvents, otelgrpc.SentEvents),
+		))),
 	}
 
-	if enableTLS {
-		tlsCredentials, err := creds.LoadClientTLSCredentials(mutualTLS)
+	if cfg.TLS.Enabled {
+		tlsCredentials, err := creds.LoadClientTLSCredentials(cfg.TLS.Mutual)
 		if err != nil {
 			logger.With("error", err).Error("cannot load client TLS credentials")
 			os.Exit(1)
 		}
 
-		clientOptions = append(clientOptions, grpc.WithTransportCredentials(tlsCredentials))
+		clientOptions = append(clientOptions, client.WithTransportCredentials(tlsCredentials))
 	}
 
-	conn, err := grpc.NewClient(address, clientOptions...)
+	c, err := client.New(address, clientOptions...)
 	if err != nil {
 		logger.With("error", err).Error("[ERROR] grpc client did not connect")
 		return
 	}
-	defer conn.Close()
+	defer c.Close()
+
+	if cfg.HealthCheck.Enabled {
+		code := checkHealth(c.Conn(), cfg.HealthCheck.Service)
+		_ = c.Close()
+		os.Exit(code)
+	}
 
-	client := pb.NewChatClient(conn)
+	// graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	switch cfg.Mode {
+	case "generate":
+		err = runGenerate(ctx, c, cfg, logger.Logger)
+	case "tui":
+		err = runTUI(ctx, c, cfg)
+	default:
+		err = runInteractive(ctx, c, cfg, os.Stdin, os.Stdout)
+	}
 
-	stream, err := client.ChatStream(parentCtx)
 	if err != nil {
-		logger.With(slog.String("error", err.Error())).Error("[ERROR] creating stream failed")
+		logger.With(slog.String("error", err.Error())).Error("chat stream failed")
 		return
 	}
+	logger.Warn("Bye!")
+}
 
-	// Горутина получения сообщений
-	go func() {
-		defer cancel()
-		for {
-			select {
-			case <-parentCtx.Done():
-				logger.Debug("[RECEIVER] Received signal, shutting down")
-				return
-			default:
-				in, err := stream.Recv()
-
-				// Ловим отмену контекста и переходим на обработку канала done в select
-				if status.Code(err) == codes.Canceled {
-					continue
-				}
-
-				if errors.Is(err, io.EOF) {
-					// Сервер прекратил отправку или контекст завершен
-					return
-				}
-
-				if err != nil {
-					logger.With(slog.String("error", err.Error())).Error("Failed to receive a message")
-					return
-				}
-				logger.With("body", in.Body).Debug("got server message")
-			}
-		}
-	}()
-
-	// Горутина отправки сообщений
-	go func() {
-		ticker := time.NewTicker(time.Second)
-		for {
-			select {
-			case <-parentCtx.Done():
-				logger.Debug("[SENDER] Received signal, shutting down")
-				return
-			case <-ticker.C:
-				msg := gofakeit.Name() + " want to drink " + gofakeit.BeerName()
-				if err = stream.Send(&pb.Message{Body: msg}); err != nil {
-					logger.With(slog.String("error", err.Error())).Error("Failed to send a message")
-				}
-			}
-		}
-	}()
+func checkHealth(conn *grpc.ClientConn, service string) int {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 
-	// graceful shutdown
-	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+	if err != nil {
+		slog.With("service", service, "error", err).Error("health check failed")
+		return 2
+	}
 
-	go func() {
-		<-stop
-		logger.Debug("shutting down...")
-		err = stream.CloseSend()
-		if err != nil {
-			logger.With(slog.String("error", err.Error())).Error("Failed to close stream")
-			return
-		}
-		logger.Debug("Closed stream")
-		cancel()
-	}()
+	slog.With("service", service, "status", resp.Status).Info("health check")
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		return 1
+	}
 
-	<-parentCtx.Done()
-	logger.Warn("Bye!")
+	return 0
 }
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"grpc-streaming/internal/config"
+	"grpc-streaming/pkg/client"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	pb "grpc-streaming/streaming/grpc"
+)
+
+const (
+	statusInterval = time.Second
+	roomsInterval  = 5 * time.Second
+)
+
+// tui is the full-screen front end: rooms on the left, messages on the right,
+// the input line and a connection status bar at the bottom.
+type tui struct {
+	app      *tview.Application
+	messages *tview.TextView
+	rooms    *tview.List
+	input    *tview.InputField
+	status   *tview.TextView
+
+	client *client.Client
+	cfg    *config.Client
+	// cancel stops the UI, /quit calls it without waiting for the worker.
+	cancel context.CancelFunc
+	// commands feeds the input lines to a single worker so slow RPCs never
+	// block the UI goroutine and commands keep their order.
+	commands chan string
+	// state is the last client event, shown in the status bar.
+	state string
+	// stopped is set once the UI loop returned, queued updates would block.
+	stopped atomic.Bool
+}
+
+// notice and message are called by the worker and receive goroutines, the
+// lines are written on the UI goroutine.
+func (t *tui) notice(format string, args ...any) {
+	line := noticeLine(format, args...)
+	t.update(func() { fmt.Fprintln(t.messages, line) })
+}
+
+func (t *tui) message(msg *pb.Message) {
+	line := tview.Escape(formatMessage(msg))
+	if msg.Type == pb.Message_SYSTEM || msg.Type == pb.Message_ERROR {
+		line = "[red]" + line + "[-]"
+	}
+	t.update(func() { fmt.Fprintln(t.messages, line) })
+}
+
+func noticeLine(format string, args ...any) string {
+	return "[yellow]*** " + tview.Escape(fmt.Sprintf(format, args...)) + "[-]"
+}
+
+func runTUI(ctx context.Context, c *client.Client, cfg *config.Client) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	t := &tui{
+		app:      tview.NewApplication(),
+		messages: tview.NewTextView(),
+		rooms:    tview.NewList(),
+		input:    tview.NewInputField(),
+		status:   tview.NewTextView(),
+		client:   c,
+		cfg:      cfg,
+		cancel:   cancel,
+		commands: make(chan string, 16),
+		state:    "not in a room",
+	}
+	defer c.Leave()
+
+	t.layout()
+
+	go t.receive(ctx)
+	go t.work(ctx)
+	go t.refresh(ctx)
+	go func() {
+		<-ctx.Done()
+		t.app.Stop()
+	}()
+
+	if cfg.Room != "" {
+		t.commands <- "/join " + cfg.Room
+	}
+
+	err := t.app.Run()
+	t.stopped.Store(true)
+
+	return err
+}
+
+// submit hands line to the worker from the UI goroutine without waiting for
+// it: /quit skips the queue and a full queue drops the line.
+func (t *tui) submit(line string) {
+	if strings.TrimSpace(line) == "/quit" {
+		t.cancel()
+		return
+	}
+
+	select {
+	case t.commands <- line:
+	default:
+		fmt.Fprintln(t.messages, noticeLine("still busy with the previous commands, %q was not run", line))
+	}
+}
+
+// update runs fn on the UI goroutine and redraws, it is a no-op once the UI
+// loop stopped.
+func (t *tui) update(fn func()) {
+	if t.stopped.Load() {
+		return
+	}
+
+	t.app.QueueUpdateDraw(fn)
+}
+
+func (t *tui) layout() {
+	t.messages.SetDynamicColors(true).
+		SetScrollable(true).
+		ScrollToEnd().
+		SetBorder(true).
+		SetTitle(" messages ")
+
+	t.rooms.ShowSecondaryText(false).
+		SetSelectedFunc(func(_ int, _ string, room string, _ rune) {
+			t.submit("/join " + room)
+			t.app.SetFocus(t.input)
+		}).
+		SetBorder(true).
+		SetTitle(" rooms ")
+
+	t.input.SetLabel("> ").
+		SetFieldBackgroundColor(tcell.ColorDefault).
+		SetDoneFunc(func(key tcell.Key) {
+			if key != tcell.KeyEnter {
+				return
+			}
+			t.submit(t.input.GetText())
+			t.input.SetText("")
+		})
+
+	t.status.SetDynamicColors(true)
+
+	body := tview.NewFlex().
+		AddItem(t.rooms, 24, 0, false).
+		AddItem(t.messages, 0, 1, false)
+	root := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(body, 0, 1, false).
+		AddItem(t.input, 1, 0, true).
+		AddItem(t.status, 1, 0, false)
+
+	// Tab переключает фокус между списком комнат и строкой ввода
+	t.app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() != tcell.KeyTab {
+			return event
+		}
+		if t.input.HasFocus() {
+			t.app.SetFocus(t.rooms)
+		} else {
+			t.app.SetFocus(t.input)
+		}
+		return nil
+	})
+
+	t.app.SetRoot(root, true)
+	t.updateStatus()
+}
+
+func (t *tui) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case line := <-t.commands:
+			if execute(t.client, line, t) {
+				t.cancel()
+				return
+			}
+		}
+	}
+}
+
+// receive renders the messages and events of the client.
+func (t *tui) receive(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-t.client.Messages():
+			if !ok {
+				return
+			}
+			t.message(msg)
+		case ev, ok := <-t.client.Events():
+			if !ok {
+				return
+			}
+			t.onEvent(ev)
+		}
+	}
+}
+
+func (t *tui) onEvent(ev client.Event) {
+	t.notice("%s", describeEvent(ev))
+	if ev.State == client.StateJoined || ev.State == client.StateLeft {
+		go t.refreshRooms()
+	}
+
+	t.update(func() {
+		switch ev.State {
+		case client.StateJoined:
+			t.state = "[green]in " + ev.Room + "[-]"
+			t.messages.SetTitle(" " + ev.Room + " ")
+		case client.StateReconnecting:
+			t.state = "[yellow]rejoining " + ev.Room + "[-]"
+		default:
+			t.state = "not in a room"
+			t.messages.SetTitle(" messages ")
+		}
+		t.updateStatus()
+	})
+}
+
+// refresh keeps the status bar and the room list up to date.
+func (t *tui) refresh(ctx context.Context) {
+	statusTicker := time.NewTicker(statusInterval)
+	defer statusTicker.Stop()
+	roomsTicker := time.NewTicker(roomsInterval)
+	defer roomsTicker.Stop()
+
+	t.refreshRooms()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-statusTicker.C:
+			t.update(t.updateStatus)
+		case <-roomsTicker.C:
+			t.refreshRooms()
+		}
+	}
+}
+
+func (t *tui) refreshRooms() {
+	ctx, cancel := context.WithTimeout(context.Background(), rpcTimeout)
+	defer cancel()
+
+	rooms, err := t.client.Rooms(ctx)
+	if err != nil {
+		return
+	}
+
+	t.update(func() {
+		current := t.client.Room()
+		t.rooms.Clear()
+		for _, room := range rooms {
+			label := fmt.Sprintf("%s (%d)", room.Name, room.Participants)
+			if room.Name == current {
+				label = "[green]" + label + "[-]"
+			}
+			t.rooms.AddItem(label, room.Name, 0, nil)
+		}
+	})
+}
+
+// updateStatus must run on the UI goroutine.
+func (t *tui) updateStatus() {
+	security := "insecure"
+	switch {
+	case t.cfg.TLS.Mutual:
+		security = "mutual TLS"
+	case t.cfg.TLS.Enabled:
+		security = "TLS"
+	}
+
+	connState := strings.ToLower(t.client.Conn().GetState().String())
+	t.status.SetText(fmt.Sprintf(" %s | %s | connection: %s | %s | Tab: rooms, /help",
+		tview.Escape(t.cfg.Address), security, connState, t.state))
+}
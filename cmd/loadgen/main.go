@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"grpc-streaming/internal/client/interceptors"
+	creds "grpc-streaming/internal/client/tls"
+	"grpc-streaming/internal/config"
+	"grpc-streaming/internal/logging"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	pb "grpc-streaming/streaming/grpc"
+)
+
+func main() {
+	cfg := config.DefaultLoadgen()
+	loader := config.NewLoader("loadgen", config.LoadgenEnvPrefix, cfg)
+	opts, err := loader.Load(os.Args[1:])
+	if err == nil && opts.PrintConfig {
+		err = loader.PrintConfig(os.Stdout)
+	}
+	if err == nil && opts.PrintSchema {
+		err = loader.PrintSchema(os.Stdout)
+	}
+	if err != nil {
+		slog.With("error", err).Error("invalid configuration")
+		os.Exit(1)
+	}
+	if opts.PrintConfig || opts.PrintSchema {
+		return
+	}
+
+	logger, err := logging.New(cfg.Logging.Config())
+	if err != nil {
+		slog.With("error", err).Error("cannot configure logging")
+		os.Exit(1)
+	}
+	defer logger.Close()
+	slog.SetDefault(logger.Logger)
+
+	conns := make([]*grpc.ClientConn, cfg.Connections)
+	for i := range conns {
+		conns[i], err = dial(cfg)
+		if err != nil {
+			logger.With("error", err).Error("grpc client did not connect")
+			os.Exit(1)
+		}
+		defer conns[i].Close()
+	}
+
+	rooms := cfg.Rooms
+	if rooms == 0 {
+		rooms = cfg.Streams
+	}
+
+	maxSize := 0
+	for _, size := range cfg.PayloadSizes {
+		maxSize = max(maxSize, size)
+	}
+	padding := strings.Repeat("x", maxSize)
+
+	// Каждый стрим отправляет свою долю общего темпа
+	interval := time.Duration(float64(time.Second) * float64(cfg.Streams) / cfg.Rate)
+
+	workers := make([]*worker, cfg.Streams)
+	for i := range workers {
+		client := pb.NewChatClient(conns[i%len(conns)])
+		room := "bench-" + strconv.Itoa(i%rooms)
+		workers[i] = newWorker(i, room, client, interval, cfg.EchoTimeout, cfg.PayloadSizes, padding)
+	}
+
+	logger.With("address", cfg.Address, "connections", cfg.Connections, "streams", cfg.Streams, "rooms", rooms, "rate", cfg.Rate).
+		Info("starting load")
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	sendCtx, cancel := context.WithTimeout(ctx, cfg.Duration)
+	defer cancel()
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for _, w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.run(sendCtx, cfg.Drain)
+		}()
+	}
+	<-sendCtx.Done()
+	// Эхо, пришедшее во время ожидания, не должно занижать темп
+	elapsed := time.Since(start)
+	wg.Wait()
+
+	r := newReport(cfg, rooms, elapsed, workers)
+
+	var summary io.Writer = os.Stdout
+	if cfg.Report == "-" {
+		summary = os.Stderr
+	}
+	r.print(summary)
+
+	if cfg.Report != "" {
+		if err := r.export(cfg.Report); err != nil {
+			logger.With("error", err).Error("cannot write report")
+			os.Exit(1)
+		}
+	}
+}
+
+func dial(cfg *config.Loadgen) (*grpc.ClientConn, error) {
+	auth := interceptors.NewAuthClientInterceptor(cfg.Token)
+	requestID := interceptors.NewRequestIDClientInterceptor()
+	options := []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(requestID.Unary(), auth.Unary()),
+		grpc.WithChainStreamInterceptor(requestID.Stream(), auth.Stream()),
+	}
+
+	if cfg.TLS.Enabled {
+		tlsCredentials, err := creds.LoadClientTLSCredentials(cfg.TLS.Mutual)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load client TLS credentials: %w", err)
+		}
+
+		options = append(options, grpc.WithTransportCredentials(tlsCredentials))
+	}
+
+	return grpc.NewClient(cfg.Address, options...)
+}
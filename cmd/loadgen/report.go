@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"grpc-streaming/internal/config"
+)
+
+// report is the result of a run, written as JSON with -report.
+type report struct {
+	Address      string  `json:"address"`
+	Connections  int     `json:"connections"`
+	Streams      int     `json:"streams"`
+	Rooms        int     `json:"rooms"`
+	TargetRate   float64 `json:"target_rate"`
+	PayloadSizes []int   `json:"payload_sizes"`
+	Elapsed      float64 `json:"elapsed_seconds"`
+
+	Sent      uint64 `json:"sent"`
+	BytesSent uint64 `json:"bytes_sent"`
+	Echoed    uint64 `json:"echoed"`
+	Delivered uint64 `json:"delivered"`
+	Lost      uint64 `json:"lost"`
+
+	SendRate     float64 `json:"send_rate"`
+	EchoRate     float64 `json:"echo_rate"`
+	DeliveryRate float64 `json:"delivery_rate"`
+
+	Latency latency `json:"latency_ms"`
+
+	Reopened uint64         `json:"reopened_streams"`
+	Errors   map[string]int `json:"errors"`
+}
+
+// latency summarizes the round trips in milliseconds.
+type latency struct {
+	Min  float64 `json:"min"`
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+	P99  float64 `json:"p99"`
+	Max  float64 `json:"max"`
+}
+
+func newReport(cfg *config.Loadgen, rooms int, elapsed time.Duration, workers []*worker) *report {
+	r := &report{
+		Address:      cfg.Address,
+		Connections:  cfg.Connections,
+		Streams:      cfg.Streams,
+		Rooms:        rooms,
+		TargetRate:   cfg.Rate,
+		PayloadSizes: cfg.PayloadSizes,
+		Elapsed:      elapsed.Seconds(),
+		Errors:       make(map[string]int),
+	}
+
+	var latencies []time.Duration
+	for _, w := range workers {
+		r.Sent += w.sent.Load()
+		r.BytesSent += w.bytesSent.Load()
+		r.Echoed += w.echoed.Load()
+		r.Delivered += w.delivered.Load()
+		r.Reopened += w.reopened.Load()
+
+		w.mu.Lock()
+		latencies = append(latencies, w.latencies...)
+		for code, n := range w.errors {
+			r.Errors[code] += n
+		}
+		w.mu.Unlock()
+	}
+
+	r.Lost = r.Sent - r.Echoed
+	if seconds := elapsed.Seconds(); seconds > 0 {
+		r.SendRate = float64(r.Sent) / seconds
+		r.EchoRate = float64(r.Echoed) / seconds
+		r.DeliveryRate = float64(r.Delivered) / seconds
+	}
+	r.Latency = summarize(latencies)
+
+	return r
+}
+
+func summarize(latencies []time.Duration) latency {
+	if len(latencies) == 0 {
+		return latency{}
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	var total time.Duration
+	for _, l := range latencies {
+		total += l
+	}
+
+	return latency{
+		Min:  ms(latencies[0]),
+		Mean: ms(total / time.Duration(len(latencies))),
+		P50:  ms(percentile(latencies, 50)),
+		P90:  ms(percentile(latencies, 90)),
+		P99:  ms(percentile(latencies, 99)),
+		Max:  ms(latencies[len(latencies)-1]),
+	}
+}
+
+// percentile uses the nearest rank method, sorted must not be empty.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+
+	return sorted[rank-1]
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// print writes a human readable summary.
+func (r *report) print(w io.Writer) {
+	fmt.Fprintf(w, "streams %d over %d connections in %d rooms, %.1fs\n", r.Streams, r.Connections, r.Rooms, r.Elapsed)
+	fmt.Fprintf(w, "sent      %d (%.1f msg/s, target %.1f), %d bytes\n", r.Sent, r.SendRate, r.TargetRate, r.BytesSent)
+	fmt.Fprintf(w, "echoed    %d (%.1f msg/s), lost %d\n", r.Echoed, r.EchoRate, r.Lost)
+	fmt.Fprintf(w, "delivered %d (%.1f msg/s)\n", r.Delivered, r.DeliveryRate)
+	fmt.Fprintf(w, "latency   min %.2fms mean %.2fms p50 %.2fms p90 %.2fms p99 %.2fms max %.2fms\n",
+		r.Latency.Min, r.Latency.Mean, r.Latency.P50, r.Latency.P90, r.Latency.P99, r.Latency.Max)
+
+	if len(r.Errors) == 0 {
+		fmt.Fprintln(w, "errors    none")
+		return
+	}
+
+	codes := make([]string, 0, len(r.Errors))
+	for code := range r.Errors {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		fmt.Fprintf(w, "errors    %s: %d\n", code, r.Errors[code])
+	}
+	fmt.Fprintf(w, "reopened  %d streams\n", r.Reopened)
+}
+
+// export writes the report as JSON to path, - is stdout.
+func (r *report) export(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if path == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o644)
+}
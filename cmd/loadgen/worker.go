@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	pb "grpc-streaming/streaming/grpc"
+)
+
+// reopenDelay is the pause before a failed stream is opened again.
+const reopenDelay = 100 * time.Millisecond
+
+// worker drives a single ChatStream at a fixed rate. Every body starts with
+// "<worker id>:<sequence>:" so the worker recognizes the echo of its own
+// messages among the room traffic and measures the round trip.
+type worker struct {
+	id       int
+	room     string
+	client   pb.ChatClient
+	interval time.Duration
+	// echoTimeout bounds pending, older messages are taken for lost
+	echoTimeout time.Duration
+	sizes       []int
+	padding     string
+
+	sent      atomic.Uint64
+	bytesSent atomic.Uint64
+	echoed    atomic.Uint64
+	delivered atomic.Uint64
+	reopened  atomic.Uint64
+
+	mu        sync.Mutex
+	pending   map[uint64]time.Time
+	latencies []time.Duration
+	errors    map[string]int
+}
+
+func newWorker(id int, room string, client pb.ChatClient, interval, echoTimeout time.Duration, sizes []int, padding string) *worker {
+	return &worker{
+		id:          id,
+		room:        room,
+		client:      client,
+		interval:    interval,
+		echoTimeout: echoTimeout,
+		sizes:       sizes,
+		padding:     padding,
+		pending:     make(map[uint64]time.Time),
+		errors:      make(map[string]int),
+	}
+}
+
+// run sends until sendCtx is done, then waits up to drain for the echoes of
+// the last messages. Failed streams are counted and opened again.
+func (w *worker) run(sendCtx context.Context, drain time.Duration) {
+	// Разносим старт воркеров во времени, чтобы не отправлять пачками
+	select {
+	case <-sendCtx.Done():
+		return
+	case <-time.After(time.Duration(rand.Int63n(int64(w.interval) + 1))):
+	}
+
+	var seq uint64
+	for sendCtx.Err() == nil {
+		err := w.stream(sendCtx, drain, &seq)
+		if err == nil {
+			return
+		}
+
+		w.fail(err)
+		w.reopened.Add(1)
+		select {
+		case <-sendCtx.Done():
+			return
+		case <-time.After(reopenDelay):
+		}
+	}
+}
+
+func (w *worker) stream(sendCtx context.Context, drain time.Duration, seq *uint64) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := w.client.ChatStream(metadata.AppendToOutgoingContext(ctx, "room", w.room))
+	if err != nil {
+		return err
+	}
+
+	recvErr := make(chan error, 1)
+	go func() {
+		recvErr <- w.receive(stream)
+	}()
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	expire := time.NewTicker(w.echoTimeout)
+	defer expire.Stop()
+
+	for {
+		select {
+		case err := <-recvErr:
+			if err == nil {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		case <-sendCtx.Done():
+			// Даем эхо последних сообщений дойти, затем закрываем стрим
+			_ = stream.CloseSend()
+			select {
+			case err := <-recvErr:
+				return err
+			case <-time.After(drain):
+				return nil
+			}
+		case now := <-expire.C:
+			w.expire(now.Add(-w.echoTimeout))
+		case <-ticker.C:
+			*seq++
+			body := w.body(*seq)
+
+			w.mu.Lock()
+			w.pending[*seq] = time.Now()
+			w.mu.Unlock()
+
+			if err := stream.Send(&pb.Message{Body: body}); err != nil {
+				// Настоящую причину вернет Recv
+				return <-recvErr
+			}
+			w.sent.Add(1)
+			w.bytesSent.Add(uint64(len(body)))
+		}
+	}
+}
+
+func (w *worker) receive(stream pb.Chat_ChatStreamClient) error {
+	for {
+		msg, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if msg.Type != pb.Message_CHAT {
+			continue
+		}
+
+		w.delivered.Add(1)
+		id, seq, ok := parseBody(msg.Body)
+		if !ok || id != w.id {
+			continue
+		}
+
+		w.mu.Lock()
+		if sentAt, ok := w.pending[seq]; ok {
+			delete(w.pending, seq)
+			w.latencies = append(w.latencies, time.Since(sentAt))
+			w.echoed.Add(1)
+		}
+		w.mu.Unlock()
+	}
+}
+
+// expire forgets the messages sent before deadline, they stay counted as lost
+// and a late echo is ignored.
+func (w *worker) expire(deadline time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for seq, sentAt := range w.pending {
+		if sentAt.Before(deadline) {
+			delete(w.pending, seq)
+		}
+	}
+}
+
+func (w *worker) body(seq uint64) string {
+	prefix := fmt.Sprintf("%d:%d:", w.id, seq)
+	size := w.sizes[seq%uint64(len(w.sizes))]
+	if size <= len(prefix) {
+		return prefix
+	}
+
+	return prefix + w.padding[:size-len(prefix)]
+}
+
+func (w *worker) fail(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.errors[status.Code(err).String()]++
+}
+
+func parseBody(body string) (int, uint64, bool) {
+	parts := strings.SplitN(body, ":", 3)
+	if len(parts) < 3 {
+		return 0, 0, false
+	}
+
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	seq, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return id, seq, true
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"grpc-streaming/internal/logging"
+	"grpc-streaming/internal/server/audit"
+	"grpc-streaming/internal/server/history"
+	"grpc-streaming/internal/server/hub"
+	"grpc-streaming/internal/server/interceptors"
+	creds "grpc-streaming/internal/server/tls"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	pb "grpc-streaming/streaming/grpc"
+)
+
+type adminServer struct {
+	pb.UnimplementedAdminServer
+	hub     *hub.Hub
+	history *history.Store
+	audit   audit.Sink
+	// level is the runtime adjustable level of the server logger.
+	level *slog.LevelVar
+
+	enableTLS bool
+	mutualTLS bool
+}
+
+func (s *adminServer) ListStreams(_ context.Context, req *pb.ListStreamsRequest) (*pb.ListStreamsResponse, error) {
+	now := time.Now()
+	subs := s.hub.Subscribers(req.Room)
+
+	resp := &pb.ListStreamsResponse{Streams: make([]*pb.StreamInfo, 0, len(subs))}
+	for _, sub := range subs {
+		resp.Streams = append(resp.Streams, &pb.StreamInfo{
+			Id:         sub.StreamID,
+			Principal:  sub.Principal,
+			Peer:       sub.Peer,
+			Room:       sub.Room,
+			StartedAt:  timestamppb.New(sub.Started),
+			Uptime:     durationpb.New(now.Sub(sub.Started).Round(time.Second)),
+			Dropped:    sub.Dropped,
+			QueueDepth: int32(sub.QueueDepth),
+		})
+	}
+
+	return resp, nil
+}
+
+func (s *adminServer) KillStream(ctx context.Context, req *pb.KillStreamRequest) (*pb.KillStreamResponse, error) {
+	if req.Id == "" {
+		return nil, status.Error(codes.InvalidArgument, "stream id is required")
+	}
+
+	reason := req.Reason
+	if reason == "" {
+		reason = "disconnected by an administrator"
+	}
+
+	if !s.hub.Disconnect(req.Id, status.Error(codes.Aborted, reason)) {
+		return nil, status.Errorf(codes.NotFound, "stream %q is not connected", req.Id)
+	}
+
+	s.record(ctx, pb.Admin_KillStream_FullMethodName, "", map[string]string{
+		"action":    "kill_stream",
+		"stream_id": req.Id,
+		"reason":    reason,
+	})
+
+	return &pb.KillStreamResponse{}, nil
+}
+
+func (s *adminServer) Broadcast(ctx context.Context, req *pb.BroadcastRequest) (*pb.BroadcastResponse, error) {
+	if strings.TrimSpace(req.Body) == "" {
+		return nil, status.Error(codes.InvalidArgument, "message body is required")
+	}
+
+	rooms := []string{req.Room}
+	if req.Room == "" {
+		rooms = s.hub.Rooms()
+	}
+
+	var recipients int
+	for _, room := range rooms {
+		entry := history.Entry{
+			Time:      time.Now(),
+			Room:      room,
+			Principal: interceptors.PrincipalFromContext(ctx),
+			Type:      pb.Message_SYSTEM,
+			Body:      req.Body,
+		}
+		recipients += s.hub.Publish(room, entry.Message())
+		s.history.Append(entry)
+	}
+
+	s.record(ctx, pb.Admin_Broadcast_FullMethodName, req.Room, map[string]string{
+		"action":     "broadcast",
+		"recipients": strconv.Itoa(recipients),
+	})
+
+	return &pb.BroadcastResponse{Recipients: int32(recipients)}, nil
+}
+
+func (s *adminServer) SetLogLevel(ctx context.Context, req *pb.SetLogLevelRequest) (*pb.SetLogLevelResponse, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(req.Level)); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid log level %q", req.Level)
+	}
+
+	previous := s.level.Level()
+	s.level.Set(level)
+	logging.FromContext(ctx).With("previous", previous, "level", level).Warn("log level changed")
+
+	s.record(ctx, pb.Admin_SetLogLevel_FullMethodName, "", map[string]string{
+		"action":   "set_log_level",
+		"previous": previous.String(),
+		"level":    level.String(),
+	})
+
+	return &pb.SetLogLevelResponse{PreviousLevel: strings.ToLower(previous.String())}, nil
+}
+
+func (s *adminServer) ListRooms(context.Context, *pb.ListRoomsRequest) (*pb.ListRoomsResponse, error) {
+	subscribers := make(map[string]int)
+	for _, sub := range s.hub.Subscribers("") {
+		subscribers[sub.Room]++
+	}
+	lengths := s.history.Len()
+
+	names := s.hub.Rooms()
+	for _, room := range s.history.Rooms() {
+		if _, ok := subscribers[room]; !ok {
+			names = append(names, room)
+		}
+	}
+	sort.Strings(names)
+
+	resp := &pb.ListRoomsResponse{Rooms: make([]*pb.RoomInfo, 0, len(names))}
+	for _, room := range names {
+		resp.Rooms = append(resp.Rooms, &pb.RoomInfo{
+			Name:        room,
+			Subscribers: int32(subscribers[room]),
+			History:     int32(lengths[room]),
+		})
+	}
+
+	return resp, nil
+}
+
+func (s *adminServer) ExportHistory(req *pb.ExportHistoryRequest, stream pb.Admin_ExportHistoryServer) error {
+	if req.Room == "" {
+		return status.Error(codes.InvalidArgument, "room is required")
+	}
+	if !s.history.Enabled() {
+		return status.Error(codes.FailedPrecondition, "message history is disabled on this server")
+	}
+
+	var since time.Time
+	if req.Since != nil {
+		since = req.Since.AsTime()
+	}
+
+	entries := s.history.Entries(req.Room, since)
+	s.record(stream.Context(), pb.Admin_ExportHistory_FullMethodName, req.Room, map[string]string{
+		"action":  "export_history",
+		"entries": strconv.Itoa(len(entries)),
+	})
+
+	for _, entry := range entries {
+		err := stream.Send(&pb.HistoryEntry{
+			Time:      timestamppb.New(entry.Time),
+			Room:      entry.Room,
+			Principal: entry.Principal,
+			Type:      entry.Type,
+			Body:      entry.Body,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *adminServer) CertificateStatus(context.Context, *pb.CertificateStatusRequest) (*pb.CertificateStatusResponse, error) {
+	resp := &pb.CertificateStatusResponse{TlsEnabled: s.enableTLS, MutualTls: s.mutualTLS}
+	if !s.enableTLS {
+		return resp, nil
+	}
+
+	certs, err := creds.LoadCertificates(s.mutualTLS)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "cannot load certificates: %v", err)
+	}
+
+	for _, cert := range certs {
+		resp.Certificates = append(resp.Certificates, &pb.CertificateInfo{
+			Name:      cert.Name,
+			Subject:   cert.Subject.String(),
+			Issuer:    cert.Issuer.String(),
+			DnsNames:  cert.DNSNames,
+			NotBefore: timestamppb.New(cert.NotBefore),
+			NotAfter:  timestamppb.New(cert.NotAfter),
+		})
+	}
+
+	return resp, nil
+}
+
+// record writes an AdminAction audit event, room is empty for server wide actions.
+func (s *adminServer) record(ctx context.Context, method, room string, details map[string]string) {
+	event := interceptors.AuditEvent(ctx, audit.AdminAction, method)
+	event.Room = room
+	event.Details = details
+	interceptors.Audit(ctx, s.audit, event)
+}
@@ -1,87 +1,413 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
-	"flag"
+	"fmt"
+	"grpc-streaming/internal/config"
+	"grpc-streaming/internal/logging"
+	"grpc-streaming/internal/server/audit"
+	"grpc-streaming/internal/server/healthcheck"
+	"grpc-streaming/internal/server/hub"
 	"grpc-streaming/internal/server/interceptors"
+	"grpc-streaming/internal/server/metrics"
+	"grpc-streaming/internal/server/netlimit"
+	"grpc-streaming/internal/server/portmux"
 	creds "grpc-streaming/internal/server/tls"
-	"io"
+	"grpc-streaming/internal/tracing"
+	"grpc-streaming/pkg/server"
+	"grpc-streaming/pkg/server/pipeline"
 	"log/slog"
 	"net"
+	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
+	"time"
 
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
 	pb "grpc-streaming/streaming/grpc"
 )
 
-type server struct {
-	pb.UnimplementedChatServer
+func reportStats(
+	interval time.Duration,
+	h *hub.Hub,
+	lis *netlimit.Listener,
+	streamLimiter *interceptors.StreamLimitServerInterceptor,
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		hubStats := h.Stats()
+		slog.With(
+			"rooms", hubStats.Rooms,
+			"subscribers", hubStats.Subscribers,
+			"queueDepth", hubStats.QueueDepth,
+			"maxQueueDepth", hubStats.MaxQueueDepth,
+			"dropped", hubStats.Dropped,
+			"disconnected", hubStats.Disconnected,
+		).Debug("hub stats")
+
+		connStats := lis.Stats()
+		streamStats := streamLimiter.Stats()
+		slog.With(
+			"activeConns", connStats.Active,
+			"acceptedConns", connStats.Accepted,
+			"rejectedConns", connStats.Rejected,
+			"activeStreams", streamStats.Active,
+			"rejectedStreams", streamStats.Rejected,
+			"principals", len(streamStats.Principals),
+			"ips", len(streamStats.IPs),
+		).Debug("connection stats")
+	}
 }
 
-func (s *server) ChatStream(stream pb.Chat_ChatStreamServer) error {
-	for {
-		msg, err := stream.Recv()
-		if errors.Is(err, io.EOF) {
-			slog.Warn("client streaming finished")
-			// Если клиент завершил отправку
-			return nil
-		}
+// healthHandler answers 200 while the service of the service query parameter,
+// the whole server by default, is SERVING and 503 otherwise.
+func healthHandler(healthServer *health.Server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp, err := healthServer.Check(r.Context(), &healthpb.HealthCheckRequest{Service: r.URL.Query().Get("service")})
 		if err != nil {
-			slog.With("error", err).Error("[ERROR] client finished with error")
-			return err
+			http.Error(w, status.Convert(err).Message(), http.StatusNotFound)
+			return
 		}
 
-		slog.With("body", msg.Body).Info("Received message body from client")
-
-		// Отправляем сообщение обратно клиенту
-		if err := stream.Send(&pb.Message{Body: msg.Body}); err != nil {
-			return err
+		if resp.Status != healthpb.HealthCheckResponse_SERVING {
+			w.WriteHeader(http.StatusServiceUnavailable)
 		}
-	}
+		fmt.Fprintln(w, resp.Status)
+	})
 }
 
 func main() {
-	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
-	slog.SetDefault(logger)
+	cfg := config.DefaultServer()
+	loader := config.NewLoader("server", config.ServerEnvPrefix, cfg)
+	opts, err := loader.Load(os.Args[1:])
+	if err == nil && opts.PrintConfig {
+		err = loader.PrintConfig(os.Stdout)
+	}
+	if err == nil && opts.PrintSchema {
+		err = loader.PrintSchema(os.Stdout)
+	}
+	if err != nil {
+		slog.With("error", err).Error("invalid configuration")
+		os.Exit(1)
+	}
+	if opts.PrintConfig || opts.PrintSchema {
+		return
+	}
 
-	var port int
-	var enableTLS, mutualTLS bool
+	logger, err := logging.New(cfg.Logging.Config())
+	if err != nil {
+		slog.With("error", err).Error("cannot configure logging")
+		os.Exit(1)
+	}
+	defer logger.Close()
+	slog.SetDefault(logger.Logger)
 
-	flag.IntVar(&port, "port", 0, "the server port")
-	flag.BoolVar(&enableTLS, "tls", false, "enable SSL/TLS")
-	flag.BoolVar(&mutualTLS, "mutualTLS", false, "enable client certificate verification")
-	flag.Parse()
+	policy := cfg.SlowConsumerPolicy()
 
-	logger.With("port", port, "TLS", enableTLS, "mutualTLS", mutualTLS).Info("started server")
+	logger.With("port", cfg.Port, "TLS", cfg.TLS.Enabled, "mutualTLS", cfg.TLS.Mutual, "queueSize", cfg.Hub.QueueSize, "slowConsumer", policy).
+		Info("started server")
 
-	interceptor := interceptors.NewAuthServerInterceptor([]string{"user"})
-	serverOptions := []grpc.ServerOption{
-		grpc.UnaryInterceptor(interceptor.Unary()),
-		grpc.StreamInterceptor(interceptor.Stream()),
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing.Config("chat-server"))
+	if err != nil {
+		logger.With("error", err).Error("cannot set up tracing")
+		os.Exit(1)
 	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			logger.With("error", err).Error("failed to flush traces")
+		}
+	}()
+
+	serverMetrics := metrics.New().WithRooms(cfg.Metrics.Rooms...)
 
-	if enableTLS {
-		tlsCredentials, err := creds.LoadServerTLSCredentials(mutualTLS)
+	auditSink := audit.NopSink()
+	if cfg.Audit.Log != "" {
+		auditSink, err = audit.OpenJSONLFile(cfg.Audit.Log)
 		if err != nil {
-			logger.With("error", err).Error("cannot load TLS credentials")
+			logger.With("error", err).Error("cannot open audit log")
 			os.Exit(1)
 		}
+	}
+	defer func() {
+		if err := auditSink.Close(); err != nil {
+			logger.With("error", err).Error("failed to close audit log")
+		}
+	}()
+	rateLimiter := interceptors.NewRateLimitServerInterceptor(cfg.RateLimit.Config()).WithObserver(serverMetrics)
+	streamLimiter := interceptors.NewStreamLimitServerInterceptor(cfg.Limits.StreamLimits()).WithObserver(serverMetrics)
+
+	tcpLis, err := net.Listen("tcp", ":"+strconv.Itoa(cfg.Port))
+	if err != nil {
+		logger.With("error", err).Error("failed to listen tcp port")
+		os.Exit(1)
+	}
+	lis := netlimit.NewListener(tcpLis, cfg.Limits.MaxConns)
+
+	// В режиме одного порта gRPC получает только свои соединения от portMux
+	var portMux *portmux.Mux
+	grpcLis := net.Listener(lis)
+	if cfg.SinglePort.Enabled {
+		var tlsConfig *tls.Config
+		if cfg.TLS.Enabled {
+			if tlsConfig, err = creds.LoadServerTLSConfig(cfg.TLS.Mutual); err != nil {
+				logger.With("error", err).Error("cannot load TLS config of the single port")
+				os.Exit(1)
+			}
+		}
+
+		portMux = portmux.New(lis, portmux.Config{
+			TLS:              tlsConfig,
+			AllowPlaintext:   cfg.SinglePort.AllowPlaintext,
+			OnHandshakeError: serverMetrics.TLSHandshakeFailed,
+		})
+		grpcLis = portMux.GRPC()
+	}
+
+	serverOptions := []server.Option{
+		server.WithListener(grpcLis),
+		server.WithAudit(auditSink),
+		server.WithAuthObserver(serverMetrics),
+		server.WithUnaryInterceptors(rateLimiter.Unary()),
+		server.WithStreamInterceptors(streamLimiter.Stream(), rateLimiter.Stream(), serverMetrics.StreamInterceptor()),
+		server.WithServerOptions(
+			grpc.StatsHandler(serverMetrics.StatsHandler()),
+			grpc.StatsHandler(otelgrpc.NewServerHandler(
+				otelgrpc.WithMessageEvents(otelgrpc.ReceivedEvents, otelgrpc.SentEvents),
+			)),
+			grpc.MaxRecvMsgSize(cfg.Limits.MaxMsgSize),
+			grpc.MaxSendMsgSize(cfg.Limits.MaxMsgSize),
+			grpc.KeepaliveParams(cfg.Keepalive.Params()),
+			grpc.KeepaliveEnforcementPolicy(cfg.Keepalive.Policy()),
+		),
+		server.WithHub(cfg.Hub.QueueSize, policy),
+		server.WithHistorySize(cfg.Hub.HistorySize),
+		server.WithHeartbeat(cfg.Keepalive.HeartbeatInterval, cfg.Keepalive.IdleTimeout),
+		server.WithGRPCWebListenTimeout(cfg.GRPCWeb.ListenTimeout),
+		server.WithGatewayLimiter(rateLimiter),
+		server.WithGatewayStreamLimiter(streamLimiter),
+		server.WithWebSocketOrigins(cfg.Gateway.WebSocketOrigins...),
+	}
+
+	if cfg.Pipeline.File != "" {
+		messagePipeline, err := pipeline.Load(cfg.Pipeline.File)
+		if err != nil {
+			logger.With("error", err).Error("cannot load message pipeline")
+			os.Exit(1)
+		}
+
+		serverOptions = append(serverOptions, server.WithPipeline(messagePipeline))
+	}
+
+	if cfg.Feed.URL != "" {
+		restFeed, err := cfg.Feed.Source()
+		if err != nil {
+			logger.With("error", err).Error("cannot configure the feed")
+			os.Exit(1)
+		}
+
+		serverOptions = append(serverOptions, server.WithFeed(restFeed))
+	}
+
+	if cfg.Auth.Enabled {
+		var tokens map[string]server.Identity
+		if cfg.Auth.TokensFile != "" {
+			tokens, err = interceptors.LoadTokens(cfg.Auth.TokensFile)
+			if err != nil {
+				logger.With("error", err).Error("cannot load tokens file")
+				os.Exit(1)
+			}
+		}
 
-		serverOptions = append(serverOptions, grpc.Creds(tlsCredentials))
+		serverOptions = append(serverOptions, server.WithAuth(tokens))
 	}
 
-	grpcServer := grpc.NewServer(serverOptions...)
+	if cfg.TLS.Enabled {
+		// TLS одного порта уже завершен в portMux
+		tlsCredentials := portmux.Credentials()
+		if portMux == nil {
+			if tlsCredentials, err = creds.LoadServerTLSCredentials(cfg.TLS.Mutual); err != nil {
+				logger.With("error", err).Error("cannot load TLS credentials")
+				os.Exit(1)
+			}
+		}
+
+		serverOptions = append(serverOptions, server.WithTransportCredentials(serverMetrics.InstrumentCredentials(tlsCredentials)))
+	}
 
-	lis, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+	chatServer, err := server.New(serverOptions...)
 	if err != nil {
-		logger.With("error", err).Error("failed to listen tcp port")
+		logger.With("error", err).Error("cannot create server")
 		os.Exit(1)
 	}
+	grpcServer := chatServer.GRPCServer()
+
+	chatHub := chatServer.Hub()
+	serverMetrics.RegisterHub(chatHub)
+	serverMetrics.RegisterListener(lis)
+	if cfg.Hub.StatsInterval > 0 {
+		go reportStats(cfg.Hub.StatsInterval, chatHub, lis, streamLimiter)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if cfg.Reflection {
+		reflection.Register(grpcServer)
+	}
+
+	switch {
+	case cfg.Admin && !cfg.Auth.Enabled:
+		logger.Warn("admin service is not registered because auth is disabled")
+	case cfg.Admin:
+		pb.RegisterAdminServer(grpcServer, &adminServer{
+			hub:       chatHub,
+			history:   chatServer.History(),
+			audit:     auditSink,
+			level:     logger.Level,
+			enableTLS: cfg.TLS.Enabled,
+			mutualTLS: cfg.TLS.Mutual,
+		})
+	}
+
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+
+	healthMonitor := healthcheck.NewMonitor(healthServer, cfg.Health.Interval, pb.Chat_ServiceDesc.ServiceName)
+	if cfg.TLS.Enabled {
+		healthMonitor.AddCheck("certificate", func(context.Context) error {
+			return creds.CheckServerCertificate(cfg.Health.CertExpiryWindow)
+		})
+	}
+	if checker, ok := auditSink.(audit.Checker); ok {
+		healthMonitor.AddCheck("audit", checker.Check)
+	}
+	go healthMonitor.Run(ctx)
+
+	var metricsServer *http.Server
+	if cfg.Metrics.Addr != "" && portMux != nil {
+		// /metrics и /healthz уже отдает единый порт, второй порт не открываем
+		logger.With("addr", cfg.Metrics.Addr).Info("metrics are served on the single port, metrics addr is ignored")
+	} else if cfg.Metrics.Addr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", serverMetrics.Handler())
+		mux.Handle("/healthz", healthHandler(healthServer))
+		metricsServer = &http.Server{Addr: cfg.Metrics.Addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+		go func() {
+			logger.With("addr", cfg.Metrics.Addr).Info("serving metrics")
+			if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.With("error", err).Error("failed to serve metrics")
+			}
+		}()
+	}
+
+	gatewayHandler := chatServer.Gateway()
+	if cfg.GRPCWeb.Enabled {
+		gatewayHandler = chatServer.GRPCWeb(gatewayHandler, cfg.GRPCWeb.Origins...)
+	}
+
+	var gatewayServer *http.Server
+	if cfg.Gateway.Addr != "" {
+		gatewayServer = &http.Server{Addr: cfg.Gateway.Addr, Handler: gatewayHandler, ReadHeaderTimeout: 5 * time.Second}
+		if cfg.TLS.Enabled {
+			if gatewayServer.TLSConfig, err = creds.LoadServerTLSConfig(cfg.TLS.Mutual); err != nil {
+				logger.With("error", err).Error("cannot load TLS config of the HTTP gateway")
+				os.Exit(1)
+			}
+		}
+
+		gatewayTCPLis, err := net.Listen("tcp", cfg.Gateway.Addr)
+		if err != nil {
+			logger.With("error", err).Error("failed to listen on the HTTP gateway address")
+			os.Exit(1)
+		}
+		// Лимит соединений действует и на отдельный порт шлюза
+		gatewayLis := netlimit.NewListener(gatewayTCPLis, cfg.Limits.MaxConns)
+
+		go func() {
+			logger.With("addr", cfg.Gateway.Addr, "TLS", cfg.TLS.Enabled, "grpcWeb", cfg.GRPCWeb.Enabled).Info("serving HTTP gateway")
+			var err error
+			if gatewayServer.TLSConfig != nil {
+				err = gatewayServer.ServeTLS(gatewayLis, "", "")
+			} else {
+				err = gatewayServer.Serve(gatewayLis)
+			}
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.With("error", err).Error("failed to serve HTTP gateway")
+			}
+		}()
+	}
+
+	var portServer *http.Server
+	if portMux != nil {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", serverMetrics.Handler())
+		mux.Handle("/healthz", healthHandler(healthServer))
+		mux.Handle("/", gatewayHandler)
+		portServer = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+		go func() {
+			if err := portServer.Serve(portMux.HTTP()); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.With("error", err).Error("failed to serve HTTP on the single port")
+			}
+		}()
+		go func() {
+			logger.With("port", cfg.Port, "TLS", cfg.TLS.Enabled, "allowPlaintext", cfg.SinglePort.AllowPlaintext).Info("serving gRPC and HTTP on a single port")
+			if err := portMux.Serve(); err != nil && !errors.Is(err, net.ErrClosed) {
+				logger.With("error", err).Error("failed to accept on the single port")
+			}
+		}()
+	}
+
+	// graceful shutdown, main waits for it after Serve returns
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+		<-stop
+
+		logger.Warn("shutting down...")
+		healthMonitor.Shutdown()
+		cancel()
+
+		if metricsServer != nil {
+			shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+			_ = metricsServer.Shutdown(shutdownCtx)
+			shutdownCancel()
+		}
+
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+		defer shutdownCancel()
+		if err := chatServer.Shutdown(shutdownCtx); err != nil {
+			logger.Warn("graceful shutdown timed out, closing remaining streams")
+		}
+		if gatewayServer != nil {
+			// Потоки шлюза уже закрыты в chatServer.Shutdown
+			_ = gatewayServer.Shutdown(shutdownCtx)
+		}
+		if portServer != nil {
+			_ = portServer.Shutdown(shutdownCtx)
+			_ = portMux.Close()
+		}
+	}()
 
-	pb.RegisterChatServer(grpcServer, &server{})
-	if err = grpcServer.Serve(lis); err != nil {
+	if err = chatServer.Serve(); err != nil {
 		logger.With("error", err).Error("failed to serve grpc")
 		os.Exit(1)
 	}
+	<-shutdownDone
+	logger.Warn("Bye!")
 }
@@ -12,9 +12,9 @@ type AuthClientInterceptor struct {
 	accessToken string
 }
 
-func NewAuthClientInterceptor() *AuthClientInterceptor {
+func NewAuthClientInterceptor(accessToken string) *AuthClientInterceptor {
 	return &AuthClientInterceptor{
-		accessToken: "",
+		accessToken: accessToken,
 	}
 }
 
@@ -42,11 +42,14 @@ func (i *AuthClientInterceptor) Stream() grpc.StreamClientInterceptor {
 		opts ...grpc.CallOption,
 	) (grpc.ClientStream, error) {
 		slog.With("method", method).Debug("--> stream auth client interceptor triggered")
-		i.accessToken = "Bearer token"
 		return streamer(i.attachToken(ctx), desc, cc, method, opts...)
 	}
 }
 
 func (i *AuthClientInterceptor) attachToken(ctx context.Context) context.Context {
-	return metadata.AppendToOutgoingContext(ctx, "authorization", i.accessToken)
+	if i.accessToken == "" {
+		return ctx
+	}
+
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+i.accessToken)
 }
@@ -0,0 +1,56 @@
+package interceptors
+
+import (
+	"context"
+	"log/slog"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"grpc-streaming/internal/logging"
+)
+
+// RequestIDClientInterceptor attaches an x-request-id to every outgoing RPC
+// unless the caller already set one.
+type RequestIDClientInterceptor struct{}
+
+func NewRequestIDClientInterceptor() *RequestIDClientInterceptor {
+	return &RequestIDClientInterceptor{}
+}
+
+func (i *RequestIDClientInterceptor) Unary() grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		return invoker(i.attachRequestID(ctx, method), method, req, reply, cc, opts...)
+	}
+}
+
+func (i *RequestIDClientInterceptor) Stream() grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		return streamer(i.attachRequestID(ctx, method), desc, cc, method, opts...)
+	}
+}
+
+func (i *RequestIDClientInterceptor) attachRequestID(ctx context.Context, method string) context.Context {
+	if md, ok := metadata.FromOutgoingContext(ctx); ok && len(md.Get(logging.RequestIDMetadataKey)) > 0 {
+		return ctx
+	}
+
+	requestID := logging.NewID()
+	slog.With("method", method, "request_id", requestID).Debug("--> request id client interceptor")
+
+	return metadata.AppendToOutgoingContext(ctx, logging.RequestIDMetadataKey, requestID)
+}
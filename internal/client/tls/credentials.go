@@ -3,6 +3,7 @@ package tls
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/pem"
 	"fmt"
 	"google.golang.org/grpc/credentials"
 	"os"
@@ -62,3 +63,47 @@ func mutualTLS() (credentials.TransportCredentials, error) {
 
 	return credentials.NewTLS(config), nil
 }
+
+// Certificate is a parsed local certificate, Name is "ca" or "client".
+type Certificate struct {
+	Name string
+	*x509.Certificate
+}
+
+// LoadCertificates parses the CA certificates the server is verified against
+// and, with mutual TLS, the client certificate.
+func LoadCertificates(isMutual bool) ([]Certificate, error) {
+	pemServerCA, err := os.ReadFile("cert/ca-cert.pem")
+	if err != nil {
+		return nil, err
+	}
+
+	var certs []Certificate
+	for block, rest := pem.Decode(pemServerCA); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		ca, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, Certificate{Name: "ca", Certificate: ca})
+	}
+
+	if !isMutual {
+		return certs, nil
+	}
+
+	clientCert, err := tls.LoadX509KeyPair("cert/client-cert.pem", "cert/client-key.pem")
+	if err != nil {
+		return nil, err
+	}
+
+	leaf, err := x509.ParseCertificate(clientCert.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return append(certs, Certificate{Name: "client", Certificate: leaf}), nil
+}
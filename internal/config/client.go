@@ -0,0 +1,84 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc/keepalive"
+)
+
+const ClientEnvPrefix = "CHAT_CLIENT_"
+
+type Client struct {
+	Address string `yaml:"address" flag:"address" desc:"the server address"`
+	Room    string `yaml:"room" flag:"room" desc:"chat room to join"`
+	Token   string `yaml:"token" flag:"token" desc:"access token sent as bearer authorization" secret:"true"`
+	Mode    string `yaml:"mode" flag:"mode" desc:"interactive reads messages and slash commands from stdin, tui opens a full-screen chat, generate sends a fake message every second"`
+
+	TLS         TLS             `yaml:"tls"`
+	Keepalive   ClientKeepalive `yaml:"keepalive"`
+	HealthCheck HealthCheck     `yaml:"health_check"`
+	Tracing     Tracing         `yaml:"tracing"`
+	Logging     Logging         `yaml:"logging"`
+}
+
+type ClientKeepalive struct {
+	Time                time.Duration `yaml:"time" flag:"keepalive-time" desc:"ping the server after this much inactivity on the connection"`
+	Timeout             time.Duration `yaml:"timeout" flag:"keepalive-timeout" desc:"close the connection if a keepalive ping is not acked in time"`
+	PermitWithoutStream bool          `yaml:"permit_without_stream" flag:"keepalive-permit-without-stream" desc:"send keepalive pings even without active streams"`
+	HeartbeatInterval   time.Duration `yaml:"heartbeat_interval" flag:"heartbeat-interval" desc:"how often a heartbeat frame is sent to the server"`
+	IdleTimeout         time.Duration `yaml:"idle_timeout" flag:"idle-timeout" desc:"give up on the stream when the server sent nothing for this long"`
+}
+
+type HealthCheck struct {
+	Enabled bool   `yaml:"enabled" flag:"health-check" desc:"query the server health and exit: 0 serving, 1 not serving, 2 check failed"`
+	Service string `yaml:"service" flag:"health-service" desc:"service name for the health check, empty means overall server health"`
+}
+
+func DefaultClient() *Client {
+	// Debug-логи интерцепторов на каждый вызов мешали бы чату
+	logs := defaultLogging()
+	logs.Level = "info"
+
+	return &Client{
+		Room:  "general",
+		Token: "token",
+		Mode:  "interactive",
+		Keepalive: ClientKeepalive{
+			Time:                30 * time.Second,
+			Timeout:             10 * time.Second,
+			PermitWithoutStream: true,
+			HeartbeatInterval:   15 * time.Second,
+			IdleTimeout:         45 * time.Second,
+		},
+		Tracing: defaultTracing("client-traces.jsonl"),
+		Logging: logs,
+	}
+}
+
+func (c *Client) Validate() error {
+	var errs []error
+
+	if c.Address == "" {
+		errs = append(errs, errors.New("address: is required"))
+	}
+	if c.Mode != "interactive" && c.Mode != "tui" && c.Mode != "generate" {
+		errs = append(errs, fmt.Errorf("mode: unknown mode %q", c.Mode))
+	}
+	if c.Keepalive.HeartbeatInterval <= 0 || c.Keepalive.IdleTimeout <= c.Keepalive.HeartbeatInterval {
+		errs = append(errs, errors.New("keepalive: idle_timeout must be greater than a positive heartbeat_interval"))
+	}
+
+	errs = append(errs, c.TLS.Validate(), c.Tracing.Validate(), c.Logging.Validate())
+
+	return errors.Join(errs...)
+}
+
+func (k ClientKeepalive) Params() keepalive.ClientParameters {
+	return keepalive.ClientParameters{
+		Time:                k.Time,
+		Timeout:             k.Timeout,
+		PermitWithoutStream: k.PermitWithoutStream,
+	}
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+
+	"grpc-streaming/internal/logging"
+	"grpc-streaming/internal/tracing"
+)
+
+type TLS struct {
+	Enabled bool `yaml:"enabled" flag:"tls" desc:"enable SSL/TLS"`
+	Mutual  bool `yaml:"mutual" flag:"mutualTLS" desc:"enable mutual TLS with client certificates"`
+}
+
+type Logging struct {
+	Level     string   `yaml:"level" flag:"log-level" desc:"log level: debug, info, warn or error"`
+	Format    string   `yaml:"format" flag:"log-format" desc:"log format: text or json"`
+	Output    string   `yaml:"output" flag:"log-output" desc:"log destination: stdout, stderr, none or a file path"`
+	Redact    []string `yaml:"redact" flag:"log-redact" desc:"log attributes to mask"`
+	Sensitive bool     `yaml:"sensitive" flag:"log-sensitive" desc:"log message bodies and credentials unmasked, for debugging only"`
+}
+
+type Tracing struct {
+	Exporter    string  `yaml:"exporter" flag:"trace-exporter" desc:"OpenTelemetry trace exporter: none, stdout, file or otlp"`
+	Endpoint    string  `yaml:"endpoint" flag:"trace-endpoint" desc:"OTLP gRPC collector endpoint"`
+	Insecure    bool    `yaml:"insecure" flag:"trace-insecure" desc:"disable TLS towards the OTLP collector"`
+	File        string  `yaml:"file" flag:"trace-file" desc:"output file of the file trace exporter"`
+	SampleRatio float64 `yaml:"sample_ratio" flag:"trace-sample" desc:"fraction of new traces to sample"`
+}
+
+func defaultLogging() Logging {
+	return Logging{
+		Level:  "debug",
+		Format: "text",
+		Output: "stdout",
+		Redact: logging.DefaultRedactFields,
+	}
+}
+
+func defaultTracing(file string) Tracing {
+	return Tracing{
+		Exporter:    "none",
+		Endpoint:    "localhost:4317",
+		Insecure:    true,
+		File:        file,
+		SampleRatio: 1,
+	}
+}
+
+func (l Logging) Config() logging.Config {
+	return logging.Config{
+		Level:        l.Level,
+		Format:       l.Format,
+		Output:       l.Output,
+		RedactFields: l.Redact,
+		Sensitive:    l.Sensitive,
+	}
+}
+
+func (l Logging) Validate() error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(l.Level)); err != nil {
+		return fmt.Errorf("logging.level: %w", err)
+	}
+
+	if l.Format != "text" && l.Format != "json" {
+		return fmt.Errorf("logging.format: unknown format %q", l.Format)
+	}
+
+	return nil
+}
+
+func (t Tracing) Config(serviceName string) tracing.Config {
+	return tracing.Config{
+		Exporter:    t.Exporter,
+		Endpoint:    t.Endpoint,
+		Insecure:    t.Insecure,
+		File:        t.File,
+		ServiceName: serviceName,
+		SampleRatio: t.SampleRatio,
+	}
+}
+
+func (t Tracing) Validate() error {
+	switch t.Exporter {
+	case "none", "stdout", "file", "otlp":
+	default:
+		return fmt.Errorf("tracing.exporter: unknown exporter %q", t.Exporter)
+	}
+
+	if t.SampleRatio < 0 || t.SampleRatio > 1 {
+		return fmt.Errorf("tracing.sample_ratio: must be within [0, 1]")
+	}
+
+	return nil
+}
+
+func (t TLS) Validate() error {
+	if t.Mutual && !t.Enabled {
+		return fmt.Errorf("tls.mutual requires tls.enabled")
+	}
+
+	return nil
+}
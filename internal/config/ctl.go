@@ -0,0 +1,50 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const CtlEnvPrefix = "CHAT_CTL_"
+
+type Ctl struct {
+	Address string        `yaml:"address" flag:"address" desc:"the server address"`
+	Token   string        `yaml:"token" flag:"token" desc:"access token of a principal with the admin role" secret:"true"`
+	Output  string        `yaml:"output" flag:"output" desc:"output format: table or json"`
+	Timeout time.Duration `yaml:"timeout" flag:"timeout" desc:"deadline of a single admin call"`
+
+	TLS     TLS     `yaml:"tls"`
+	Logging Logging `yaml:"logging"`
+}
+
+func DefaultCtl() *Ctl {
+	logging := defaultLogging()
+	logging.Level = "warn"
+	logging.Output = "stderr"
+
+	return &Ctl{
+		Address: "localhost:50051",
+		Output:  "table",
+		Timeout: 10 * time.Second,
+		Logging: logging,
+	}
+}
+
+func (c *Ctl) Validate() error {
+	var errs []error
+
+	if c.Address == "" {
+		errs = append(errs, errors.New("address: is required"))
+	}
+	if c.Output != "table" && c.Output != "json" {
+		errs = append(errs, fmt.Errorf("output: unknown format %q", c.Output))
+	}
+	if c.Timeout <= 0 {
+		errs = append(errs, errors.New("timeout: must be positive"))
+	}
+
+	errs = append(errs, c.TLS.Validate(), c.Logging.Validate())
+
+	return errors.Join(errs...)
+}
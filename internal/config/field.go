@@ -0,0 +1,164 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// field is a single setting, a leaf of the config struct tree. Settings are
+// described by struct tags:
+//
+//	yaml:"port" flag:"port" desc:"the server port" secret:"true"
+type field struct {
+	path   []string
+	flag   string
+	env    string
+	desc   string
+	secret bool
+	value  reflect.Value
+	// def is the value before any file, environment or flag was applied.
+	def string
+}
+
+func (f *field) key() string {
+	return strings.Join(f.path, ".")
+}
+
+func (f *field) typeName() string {
+	if f.value.Type() == durationType {
+		return "duration"
+	}
+	if f.value.Kind() == reflect.Slice {
+		return "list"
+	}
+
+	return f.value.Kind().String()
+}
+
+// fields walks the config struct and returns its settings in declaration order.
+func fields(cfg any, envPrefix string) []*field {
+	var result []*field
+
+	var walk func(v reflect.Value, path []string)
+	walk = func(v reflect.Value, path []string) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			name, _, _ := strings.Cut(sf.Tag.Get("yaml"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+
+			fieldPath := append(append([]string(nil), path...), name)
+			if sf.Type.Kind() == reflect.Struct && sf.Type != durationType {
+				walk(v.Field(i), fieldPath)
+				continue
+			}
+
+			flagName := sf.Tag.Get("flag")
+			f := &field{
+				path:   fieldPath,
+				flag:   flagName,
+				env:    envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_")),
+				desc:   sf.Tag.Get("desc"),
+				secret: sf.Tag.Get("secret") == "true",
+				value:  v.Field(i),
+			}
+			f.def = f.String()
+			result = append(result, f)
+		}
+	}
+	walk(reflect.ValueOf(cfg).Elem(), nil)
+
+	return result
+}
+
+// String and Set make every field a flag.Value.
+func (f *field) String() string {
+	if !f.value.IsValid() {
+		return ""
+	}
+
+	switch {
+	case f.value.Type() == durationType:
+		return time.Duration(f.value.Int()).String()
+	case f.value.Kind() == reflect.Slice:
+		items := make([]string, f.value.Len())
+		for i := range items {
+			items[i] = fmt.Sprint(f.value.Index(i).Interface())
+		}
+		return strings.Join(items, ",")
+	case f.value.Kind() == reflect.Float64:
+		return strconv.FormatFloat(f.value.Float(), 'f', -1, 64)
+	}
+
+	return fmt.Sprint(f.value.Interface())
+}
+
+func (f *field) Set(s string) error {
+	switch {
+	case f.value.Type() == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		f.value.SetInt(int64(d))
+	case f.value.Kind() == reflect.String:
+		f.value.SetString(s)
+	case f.value.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		f.value.SetBool(b)
+	case f.value.Kind() == reflect.Int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		f.value.SetInt(int64(n))
+	case f.value.Kind() == reflect.Float64:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		f.value.SetFloat(n)
+	case f.value.Kind() == reflect.Slice:
+		items := reflect.MakeSlice(f.value.Type(), 0, 0)
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+
+			elem := reflect.New(f.value.Type().Elem()).Elem()
+			switch elem.Kind() {
+			case reflect.String:
+				elem.SetString(item)
+			case reflect.Int:
+				n, err := strconv.Atoi(item)
+				if err != nil {
+					return err
+				}
+				elem.SetInt(int64(n))
+			default:
+				return fmt.Errorf("unsupported setting type %s", f.value.Type())
+			}
+			items = reflect.Append(items, elem)
+		}
+		f.value.Set(items)
+	default:
+		return fmt.Errorf("unsupported setting type %s", f.value.Type())
+	}
+
+	return nil
+}
+
+// IsBoolFlag allows -tls instead of -tls=true.
+func (f *field) IsBoolFlag() bool {
+	return f.value.Kind() == reflect.Bool
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Validator is implemented by the binary configs.
+type Validator interface {
+	Validate() error
+}
+
+// Options are the config related command line switches.
+type Options struct {
+	// PrintConfig dumps the effective config with secrets masked.
+	PrintConfig bool
+	// PrintSchema documents every setting.
+	PrintSchema bool
+}
+
+// Loader binds a config struct to a config file, environment variables and
+// command line flags.
+type Loader struct {
+	cfg       Validator
+	envPrefix string
+	fields    []*field
+	flags     *flag.FlagSet
+
+	configPath string
+	options    Options
+}
+
+// NewLoader registers a flag for every setting of cfg, which must be a pointer
+// to a struct holding the defaults.
+func NewLoader(name, envPrefix string, cfg Validator) *Loader {
+	l := &Loader{
+		cfg:       cfg,
+		envPrefix: envPrefix,
+		fields:    fields(cfg, envPrefix),
+		flags:     flag.NewFlagSet(name, flag.ExitOnError),
+	}
+
+	for _, f := range l.fields {
+		l.flags.Var(f, f.flag, f.desc)
+	}
+	l.flags.StringVar(&l.configPath, "config", os.Getenv(envPrefix+"CONFIG"), "YAML config file, also read from $"+envPrefix+"CONFIG")
+	l.flags.BoolVar(&l.options.PrintConfig, "print-config", false, "print the effective config with secrets masked and exit")
+	l.flags.BoolVar(&l.options.PrintSchema, "print-schema", false, "print the documentation of every setting and exit")
+
+	return l
+}
+
+// Args returns the positional arguments left after the flags.
+func (l *Loader) Args() []string {
+	return l.flags.Args()
+}
+
+// SetUsage replaces the message printed for -h and flag errors, usage is
+// written before the flag defaults.
+func (l *Loader) SetUsage(usage string) {
+	l.flags.Usage = func() {
+		fmt.Fprint(l.flags.Output(), usage)
+		l.flags.PrintDefaults()
+	}
+}
+
+// Load applies, in this order, the config file, the environment and the
+// command line flags, then validates the result.
+func (l *Loader) Load(args []string) (Options, error) {
+	// Первый проход нужен только чтобы узнать путь к файлу конфигурации,
+	// второй восстанавливает приоритет флагов над файлом и окружением
+	if err := l.flags.Parse(args); err != nil {
+		return l.options, err
+	}
+
+	if l.configPath != "" {
+		if err := l.loadFile(l.configPath); err != nil {
+			return l.options, err
+		}
+	}
+
+	if err := l.loadEnv(); err != nil {
+		return l.options, err
+	}
+
+	if err := l.flags.Parse(args); err != nil {
+		return l.options, err
+	}
+
+	if l.options.PrintSchema {
+		return l.options, nil
+	}
+
+	return l.options, l.cfg.Validate()
+}
+
+func (l *Loader) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err = decoder.Decode(l.cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func (l *Loader) loadEnv() error {
+	for _, f := range l.fields {
+		value, ok := os.LookupEnv(f.env)
+		if !ok {
+			continue
+		}
+
+		if err := f.Set(value); err != nil {
+			return fmt.Errorf("environment variable %s: %w", f.env, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"errors"
+	"time"
+)
+
+const LoadgenEnvPrefix = "CHAT_LOADGEN_"
+
+type Loadgen struct {
+	Address string `yaml:"address" flag:"address" desc:"the server address"`
+	Token   string `yaml:"token" flag:"token" desc:"access token sent as bearer authorization" secret:"true"`
+
+	Connections  int           `yaml:"connections" flag:"connections" desc:"number of gRPC connections the streams are spread over"`
+	Streams      int           `yaml:"streams" flag:"streams" desc:"number of concurrent ChatStream streams"`
+	Rooms        int           `yaml:"rooms" flag:"rooms" desc:"number of rooms the streams are spread over, 0 puts every stream in its own room"`
+	Rate         float64       `yaml:"rate" flag:"rate" desc:"target messages per second summed over all streams"`
+	PayloadSizes []int         `yaml:"payload_sizes" flag:"payload-sizes" desc:"message body sizes in bytes, used round robin"`
+	Duration     time.Duration `yaml:"duration" flag:"duration" desc:"how long messages are sent"`
+	Drain        time.Duration `yaml:"drain" flag:"drain" desc:"how long to wait for the echoes of the last messages"`
+	EchoTimeout  time.Duration `yaml:"echo_timeout" flag:"echo-timeout" desc:"messages not echoed within this time are counted as lost and forgotten"`
+	Report       string        `yaml:"report" flag:"report" desc:"file the JSON report is written to, - for stdout, empty prints a summary only"`
+
+	TLS     TLS     `yaml:"tls"`
+	Logging Logging `yaml:"logging"`
+}
+
+func DefaultLoadgen() *Loadgen {
+	logging := defaultLogging()
+	logging.Level = "warn"
+	logging.Output = "stderr"
+
+	return &Loadgen{
+		Address:      "localhost:50051",
+		Token:        "token",
+		Connections:  4,
+		Streams:      100,
+		Rate:         1000,
+		PayloadSizes: []int{64, 256, 1024},
+		Duration:     30 * time.Second,
+		Drain:        2 * time.Second,
+		EchoTimeout:  10 * time.Second,
+		Logging:      logging,
+	}
+}
+
+func (l *Loadgen) Validate() error {
+	var errs []error
+
+	if l.Address == "" {
+		errs = append(errs, errors.New("address: is required"))
+	}
+	if l.Connections < 1 {
+		errs = append(errs, errors.New("connections: must be positive"))
+	}
+	if l.Streams < l.Connections {
+		errs = append(errs, errors.New("streams: must be at least the number of connections"))
+	}
+	if l.Rooms < 0 {
+		errs = append(errs, errors.New("rooms: must not be negative"))
+	}
+	if l.Rate <= 0 {
+		errs = append(errs, errors.New("rate: must be positive"))
+	}
+	if len(l.PayloadSizes) == 0 {
+		errs = append(errs, errors.New("payload_sizes: at least one size is required"))
+	}
+	for _, size := range l.PayloadSizes {
+		if size < 1 {
+			errs = append(errs, errors.New("payload_sizes: sizes must be positive"))
+			break
+		}
+	}
+	if l.Duration <= 0 {
+		errs = append(errs, errors.New("duration: must be positive"))
+	}
+	if l.EchoTimeout <= 0 {
+		errs = append(errs, errors.New("echo_timeout: must be positive"))
+	}
+
+	errs = append(errs, l.TLS.Validate(), l.Logging.Validate())
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+const masked = "******"
+
+// PrintConfig writes the effective config as YAML, secrets are masked.
+func (l *Loader) PrintConfig(w io.Writer) error {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+
+	for _, f := range l.fields {
+		node := root
+		for _, key := range f.path[:len(f.path)-1] {
+			node = child(node, key)
+		}
+
+		value := &yaml.Node{Kind: yaml.ScalarNode, Value: f.String()}
+		switch {
+		case f.secret && f.String() != "":
+			value.Value = masked
+		case f.value.Kind() == reflect.Slice:
+			value = &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+			for i := 0; i < f.value.Len(); i++ {
+				value.Content = append(value.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: fmt.Sprint(f.value.Index(i).Interface())})
+			}
+		case f.value.Kind() == reflect.String:
+			value.Style = yaml.DoubleQuotedStyle
+		}
+
+		key := &yaml.Node{Kind: yaml.ScalarNode, Value: f.path[len(f.path)-1], LineComment: f.desc}
+		if value.Kind == yaml.SequenceNode {
+			// Комментарий ключа перед flow-списком yaml переносит на следующую строку
+			key.LineComment, value.LineComment = "", f.desc
+		}
+		node.Content = append(node.Content, key, value)
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(root); err != nil {
+		return err
+	}
+
+	return encoder.Close()
+}
+
+// PrintSchema writes a markdown table documenting every setting.
+func (l *Loader) PrintSchema(w io.Writer) error {
+	if _, err := fmt.Fprintln(w, "| key | flag | environment | type | default | description |"); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(w, "|-----|------|-------------|------|---------|-------------|"); err != nil {
+		return err
+	}
+
+	for _, f := range l.fields {
+		def := f.def
+		if f.secret && def != "" {
+			def = masked
+		}
+
+		_, err := fmt.Fprintf(w, "| `%s` | `-%s` | `%s` | %s | `%s` | %s |\n", f.key(), f.flag, f.env, f.typeName(), def, f.desc)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func child(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	value := &yaml.Node{Kind: yaml.MappingNode}
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+
+	return value
+}
@@ -0,0 +1,263 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc/keepalive"
+
+	"grpc-streaming/internal/server/hub"
+	"grpc-streaming/internal/server/interceptors"
+	"grpc-streaming/internal/server/ratelimit"
+	"grpc-streaming/pkg/server/feed"
+)
+
+const ServerEnvPrefix = "CHAT_SERVER_"
+
+type Server struct {
+	Port            int           `yaml:"port" flag:"port" desc:"the server port"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" flag:"shutdown-timeout" desc:"how long graceful shutdown waits for streams before closing them"`
+	Reflection      bool          `yaml:"reflection" flag:"reflection" desc:"register the gRPC server reflection service, requires the admin role when auth is on"`
+	Admin           bool          `yaml:"admin" flag:"admin" desc:"register the Admin service for the admin role, it is never exposed with auth off"`
+
+	TLS        TLS             `yaml:"tls"`
+	Auth       Auth            `yaml:"auth"`
+	Hub        Hub             `yaml:"hub"`
+	Pipeline   Pipeline        `yaml:"pipeline"`
+	Feed       Feed            `yaml:"feed"`
+	Gateway    Gateway         `yaml:"gateway"`
+	GRPCWeb    GRPCWeb         `yaml:"grpc_web"`
+	SinglePort SinglePort      `yaml:"single_port"`
+	RateLimit  RateLimit       `yaml:"rate_limit"`
+	Limits     Limits          `yaml:"limits"`
+	Keepalive  ServerKeepalive `yaml:"keepalive"`
+	Health     Health          `yaml:"health"`
+	Metrics    Metrics         `yaml:"metrics"`
+	Audit      Audit           `yaml:"audit"`
+	Tracing    Tracing         `yaml:"tracing"`
+	Logging    Logging         `yaml:"logging"`
+}
+
+type Auth struct {
+	Enabled    bool   `yaml:"enabled" flag:"auth" desc:"require a bearer token for RPCs"`
+	TokensFile string `yaml:"tokens_file" flag:"tokens-file" desc:"file with '<token> <principal> <roles>' lines, empty accepts any token as 'user'"`
+}
+
+type Hub struct {
+	QueueSize     int           `yaml:"queue_size" flag:"queue-size" desc:"outbound queue size per subscriber"`
+	SlowConsumer  string        `yaml:"slow_consumer" flag:"slow-consumer" desc:"slow consumer policy: drop-oldest, drop-newest or disconnect"`
+	StatsInterval time.Duration `yaml:"stats_interval" flag:"stats-interval" desc:"how often hub and connection stats are logged, 0 disables"`
+	HistorySize   int           `yaml:"history_size" flag:"history-size" desc:"messages kept in memory per room for the admin history export, 0 disables"`
+}
+
+type Pipeline struct {
+	File string `yaml:"file" flag:"pipeline-file" desc:"YAML file with the per room message processors, empty publishes messages unchanged"`
+}
+
+type Feed struct {
+	URL        string        `yaml:"url" flag:"feed-url" desc:"REST endpoint returning a JSON array or NDJSON for the Feed RPC, empty disables it"`
+	Token      string        `yaml:"token" flag:"feed-token" desc:"bearer token sent to the feed endpoint" secret:"true"`
+	Timeout    time.Duration `yaml:"timeout" flag:"feed-timeout" desc:"deadline of a feed call including reading the response, 0 disables"`
+	ItemsField string        `yaml:"items_field" flag:"feed-items-field" desc:"field holding the array when the response is a JSON object"`
+	Mapping    []string      `yaml:"mapping" flag:"feed-mapping" desc:"target=path pairs mapping elements to messages, e.g. body=text, empty decodes them as protojson"`
+}
+
+type Gateway struct {
+	Addr             string   `yaml:"addr" flag:"gateway-addr" desc:"address of the HTTP/JSON and WebSocket gateway, served with the gRPC TLS settings, empty disables"`
+	WebSocketOrigins []string `yaml:"websocket_origins" flag:"websocket-origins" desc:"origins of the browser pages allowed to open WebSockets besides the gateway's own, * allows all"`
+}
+
+type GRPCWeb struct {
+	Enabled bool     `yaml:"enabled" flag:"grpc-web" desc:"serve gRPC-Web, text mode included, on the gateway listener and the single port"`
+	Origins []string `yaml:"origins" flag:"grpc-web-origins" desc:"origins of the browser pages CORS lets call gRPC-Web, * allows all"`
+	// ListenTimeout bounds a browser ChatStream, it sends no heartbeats
+	ListenTimeout time.Duration `yaml:"listen_timeout" flag:"grpc-web-listen-timeout" desc:"close a gRPC-Web ChatStream that only reads the room after this long, the browser reconnects"`
+}
+
+type SinglePort struct {
+	Enabled        bool `yaml:"enabled" flag:"single-port" desc:"serve the gateway, gRPC-Web and the unauthenticated /metrics and /healthz on the gRPC port, the protocol is detected per connection"`
+	AllowPlaintext bool `yaml:"allow_plaintext" flag:"allow-plaintext" desc:"with TLS on, also accept plaintext connections on the single port"`
+}
+
+type RateLimit struct {
+	PrincipalMessages float64       `yaml:"principal_messages" flag:"rl-principal-msgs" desc:"messages per second allowed per principal, 0 disables"`
+	PrincipalBytes    float64       `yaml:"principal_bytes" flag:"rl-principal-bytes" desc:"bytes per second allowed per principal, 0 disables"`
+	IPMessages        float64       `yaml:"ip_messages" flag:"rl-ip-msgs" desc:"messages per second allowed per client IP, 0 disables"`
+	IPBytes           float64       `yaml:"ip_bytes" flag:"rl-ip-bytes" desc:"bytes per second allowed per client IP, 0 disables"`
+	RoomMessages      float64       `yaml:"room_messages" flag:"rl-room-msgs" desc:"messages per second allowed per room, 0 disables"`
+	RoomBytes         float64       `yaml:"room_bytes" flag:"rl-room-bytes" desc:"bytes per second allowed per room, 0 disables"`
+	Burst             time.Duration `yaml:"burst" flag:"rl-burst" desc:"rate limit burst, expressed as time worth of traffic"`
+}
+
+type Limits struct {
+	MaxConns               int `yaml:"max_conns" flag:"max-conns" desc:"max concurrent connections on the listener, 0 means unlimited"`
+	MaxStreamsPerPrincipal int `yaml:"max_streams_per_principal" flag:"max-streams-per-principal" desc:"max concurrent streams per principal, 0 means unlimited"`
+	MaxStreamsPerIP        int `yaml:"max_streams_per_ip" flag:"max-streams-per-ip" desc:"max concurrent streams per source IP, 0 means unlimited"`
+	MaxMsgSize             int `yaml:"max_msg_size" flag:"max-msg-size" desc:"max size in bytes of a single received or sent message"`
+}
+
+type ServerKeepalive struct {
+	Time                time.Duration `yaml:"time" flag:"keepalive-time" desc:"ping the client after this much inactivity on the connection"`
+	Timeout             time.Duration `yaml:"timeout" flag:"keepalive-timeout" desc:"close the connection if a keepalive ping is not acked in time"`
+	MaxConnIdle         time.Duration `yaml:"max_conn_idle" flag:"max-conn-idle" desc:"close connections without active streams after this time, 0 means infinity"`
+	MinTime             time.Duration `yaml:"min_time" flag:"keepalive-min-time" desc:"minimum interval between client keepalive pings"`
+	PermitWithoutStream bool          `yaml:"permit_without_stream" flag:"keepalive-permit-without-stream" desc:"allow client keepalive pings when there are no active streams"`
+	HeartbeatInterval   time.Duration `yaml:"heartbeat_interval" flag:"heartbeat-interval" desc:"how often a heartbeat frame is sent on ChatStream"`
+	IdleTimeout         time.Duration `yaml:"idle_timeout" flag:"idle-timeout" desc:"close ChatStream when the client sent nothing, heartbeats included, for this long"`
+}
+
+type Health struct {
+	Interval         time.Duration `yaml:"interval" flag:"health-interval" desc:"how often subsystem health checks run"`
+	CertExpiryWindow time.Duration `yaml:"cert_expiry_window" flag:"cert-expiry-window" desc:"report unhealthy when the server certificate expires within this window"`
+}
+
+type Metrics struct {
+	Addr  string   `yaml:"addr" flag:"metrics-addr" desc:"address of the Prometheus /metrics HTTP endpoint, empty disables, ignored with single_port.enabled"`
+	Rooms []string `yaml:"rooms" flag:"metrics-rooms" desc:"rooms labelled in the message metrics, the others are counted as other"`
+}
+
+type Audit struct {
+	Log string `yaml:"log" flag:"audit-log" desc:"append-only JSON lines audit log file, empty disables auditing"`
+}
+
+func DefaultServer() *Server {
+	return &Server{
+		ShutdownTimeout: 10 * time.Second,
+		Admin:           true,
+		Auth:            Auth{Enabled: true},
+		Hub: Hub{
+			QueueSize:     64,
+			SlowConsumer:  "drop-oldest",
+			StatsInterval: 30 * time.Second,
+			HistorySize:   100,
+		},
+		RateLimit: RateLimit{
+			PrincipalMessages: 20,
+			PrincipalBytes:    64 << 10,
+			IPMessages:        50,
+			IPBytes:           256 << 10,
+			RoomMessages:      200,
+			RoomBytes:         1 << 20,
+			Burst:             2 * time.Second,
+		},
+		Limits: Limits{
+			MaxConns:               1000,
+			MaxStreamsPerPrincipal: 10,
+			MaxStreamsPerIP:        100,
+			MaxMsgSize:             64 << 10,
+		},
+		Keepalive: ServerKeepalive{
+			Time:                30 * time.Second,
+			Timeout:             10 * time.Second,
+			MinTime:             10 * time.Second,
+			PermitWithoutStream: true,
+			HeartbeatInterval:   15 * time.Second,
+			IdleTimeout:         45 * time.Second,
+		},
+		Health: Health{
+			Interval:         10 * time.Second,
+			CertExpiryWindow: 24 * time.Hour,
+		},
+		GRPCWeb: GRPCWeb{ListenTimeout: 30 * time.Minute},
+		Feed:    Feed{Timeout: 30 * time.Second},
+		Metrics: Metrics{Addr: ":9090", Rooms: []string{hub.DefaultRoom}},
+		Tracing: defaultTracing("traces.jsonl"),
+		Logging: defaultLogging(),
+	}
+}
+
+func (s *Server) Validate() error {
+	var errs []error
+
+	if s.Port < 0 || s.Port > 65535 {
+		errs = append(errs, fmt.Errorf("port: %d is out of range", s.Port))
+	}
+	if _, err := hub.ParsePolicy(s.Hub.SlowConsumer); err != nil {
+		errs = append(errs, fmt.Errorf("hub.slow_consumer: %w", err))
+	}
+	if s.Hub.QueueSize < 1 {
+		errs = append(errs, errors.New("hub.queue_size: must be positive"))
+	}
+	if s.Hub.HistorySize < 0 {
+		errs = append(errs, errors.New("hub.history_size: must not be negative"))
+	}
+	if s.Limits.MaxMsgSize < 1 {
+		errs = append(errs, errors.New("limits.max_msg_size: must be positive"))
+	}
+	if s.Keepalive.HeartbeatInterval <= 0 || s.Keepalive.IdleTimeout <= s.Keepalive.HeartbeatInterval {
+		errs = append(errs, errors.New("keepalive: idle_timeout must be greater than a positive heartbeat_interval"))
+	}
+	if s.Feed.URL != "" {
+		if _, err := s.Feed.Source(); err != nil {
+			errs = append(errs, fmt.Errorf("feed: %w", err))
+		}
+	}
+	if s.GRPCWeb.Enabled && s.Gateway.Addr == "" && !s.SinglePort.Enabled {
+		errs = append(errs, errors.New("grpc_web.enabled: requires gateway.addr or single_port.enabled"))
+	}
+	if s.GRPCWeb.ListenTimeout <= 0 {
+		errs = append(errs, errors.New("grpc_web.listen_timeout: must be positive"))
+	}
+	if s.SinglePort.AllowPlaintext && !s.SinglePort.Enabled {
+		errs = append(errs, errors.New("single_port.allow_plaintext: requires single_port.enabled"))
+	}
+	if s.Health.Interval <= 0 {
+		errs = append(errs, errors.New("health.interval: must be positive"))
+	}
+
+	errs = append(errs, s.TLS.Validate(), s.Tracing.Validate(), s.Logging.Validate())
+
+	return errors.Join(errs...)
+}
+
+// Source builds the REST feed of the Feed RPC.
+func (f Feed) Source() (*feed.REST, error) {
+	mapping, err := feed.ParseMapping(f.Mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	return feed.NewREST(feed.Config{
+		URL:        f.URL,
+		Token:      f.Token,
+		ItemsField: f.ItemsField,
+		Mapping:    mapping,
+		Timeout:    f.Timeout,
+	})
+}
+
+func (s *Server) SlowConsumerPolicy() hub.Policy {
+	policy, _ := hub.ParsePolicy(s.Hub.SlowConsumer)
+	return policy
+}
+
+func (r RateLimit) Config() interceptors.RateLimitConfig {
+	return interceptors.RateLimitConfig{
+		Principal: ratelimit.Limit{Messages: r.PrincipalMessages, Bytes: r.PrincipalBytes, Burst: r.Burst},
+		IP:        ratelimit.Limit{Messages: r.IPMessages, Bytes: r.IPBytes, Burst: r.Burst},
+		Room:      ratelimit.Limit{Messages: r.RoomMessages, Bytes: r.RoomBytes, Burst: r.Burst},
+	}
+}
+
+func (l Limits) StreamLimits() interceptors.StreamLimitConfig {
+	return interceptors.StreamLimitConfig{
+		MaxPerPrincipal: l.MaxStreamsPerPrincipal,
+		MaxPerIP:        l.MaxStreamsPerIP,
+	}
+}
+
+func (k ServerKeepalive) Params() keepalive.ServerParameters {
+	return keepalive.ServerParameters{
+		Time:              k.Time,
+		Timeout:           k.Timeout,
+		MaxConnectionIdle: k.MaxConnIdle,
+	}
+}
+
+func (k ServerKeepalive) Policy() keepalive.EnforcementPolicy {
+	return keepalive.EnforcementPolicy{
+		MinTime:             k.MinTime,
+		PermitWithoutStream: k.PermitWithoutStream,
+	}
+}
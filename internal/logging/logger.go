@@ -0,0 +1,103 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+type Config struct {
+	// Level is one of debug, info, warn or error.
+	Level string
+	// Format is text or json.
+	Format string
+	// Output is stdout, stderr, none or a file path.
+	Output string
+	// RedactFields are attribute keys whose values are masked.
+	RedactFields []string
+	// Sensitive disables redaction, meant for local debugging only.
+	Sensitive bool
+}
+
+// DefaultRedactFields mask message bodies and credentials.
+var DefaultRedactFields = []string{"body", "authorization", "token", "access_token", "password"}
+
+// Logger is a configured slog.Logger whose level can be changed at runtime.
+type Logger struct {
+	*slog.Logger
+	Level *slog.LevelVar
+
+	closer io.Closer
+}
+
+func New(config Config) (*Logger, error) {
+	level := new(slog.LevelVar)
+	if err := level.UnmarshalText([]byte(config.Level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q: %w", config.Level, err)
+	}
+
+	out, closer, err := openOutput(config.Output)
+	if err != nil {
+		return nil, err
+	}
+
+	options := &slog.HandlerOptions{Level: level}
+	if !config.Sensitive {
+		options.ReplaceAttr = NewRedactor(config.RedactFields).ReplaceAttr
+	}
+
+	var handler slog.Handler
+	switch config.Format {
+	case "", "text":
+		handler = slog.NewTextHandler(out, options)
+	case "json":
+		handler = slog.NewJSONHandler(out, options)
+	default:
+		if closer != nil {
+			_ = closer.Close()
+		}
+		return nil, fmt.Errorf("unknown log format %q", config.Format)
+	}
+
+	return &Logger{Logger: slog.New(handler), Level: level, closer: closer}, nil
+}
+
+func (l *Logger) Close() error {
+	if l.closer == nil {
+		return nil
+	}
+
+	return l.closer.Close()
+}
+
+func openOutput(output string) (io.Writer, io.Closer, error) {
+	switch output {
+	case "", "stdout":
+		return os.Stdout, nil, nil
+	case "stderr":
+		return os.Stderr, nil, nil
+	case "none":
+		return io.Discard, nil, nil
+	}
+
+	file, err := os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return file, file, nil
+}
+
+// ParseFields splits a comma separated list of attribute keys.
+func ParseFields(s string) []string {
+	var fields []string
+	for _, field := range strings.Split(s, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+
+	return fields
+}
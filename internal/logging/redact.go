@@ -0,0 +1,46 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+var bearerToken = regexp.MustCompile(`(?i)(bearer\s+)\S+`)
+
+// Redactor masks configured attributes and bearer tokens leaking into any
+// string value, e.g. inside an error message.
+type Redactor struct {
+	fields map[string]bool
+}
+
+func NewRedactor(fields []string) *Redactor {
+	r := &Redactor{fields: make(map[string]bool, len(fields))}
+	for _, field := range fields {
+		r.fields[strings.ToLower(field)] = true
+	}
+
+	return r
+}
+
+// ReplaceAttr is meant for slog.HandlerOptions.
+func (r *Redactor) ReplaceAttr(_ []string, attr slog.Attr) slog.Attr {
+	if r.fields[strings.ToLower(attr.Key)] {
+		return slog.String(attr.Key, redacted)
+	}
+
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		if value := attr.Value.String(); bearerToken.MatchString(value) {
+			return slog.String(attr.Key, bearerToken.ReplaceAllString(value, "${1}"+redacted))
+		}
+	case slog.KindAny:
+		if err, ok := attr.Value.Any().(error); ok && bearerToken.MatchString(err.Error()) {
+			return slog.String(attr.Key, bearerToken.ReplaceAllString(err.Error(), "${1}"+redacted))
+		}
+	}
+
+	return attr
+}
@@ -0,0 +1,37 @@
+package logging
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedactor(t *testing.T) {
+	redactor := NewRedactor(DefaultRedactFields)
+
+	tests := []struct {
+		name string
+		attr slog.Attr
+		leak string
+	}{
+		{"configured key", slog.String("Authorization", "Bearer abc"), "abc"},
+		{"token key", slog.String("token", "abc"), "abc"},
+		{"bearer in a string", slog.String("error", "rpc failed: bearer abc rejected"), "abc"},
+		{"bearer in an error", slog.Any("error", errors.New("Bearer abc is invalid")), "abc"},
+		{"principal with a bearer token", slog.String("principal", "Bearer abc"), "abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := redactor.ReplaceAttr(nil, tt.attr).Value.String()
+			if strings.Contains(got, tt.leak) {
+				t.Errorf("%q leaked in %q", tt.leak, got)
+			}
+		})
+	}
+
+	// Непрозрачный principal без токена логируется как есть
+	if got := redactor.ReplaceAttr(nil, slog.String("principal", "anon-0123456789ab")).Value.String(); got != "anon-0123456789ab" {
+		t.Errorf("principal = %q, want it unchanged", got)
+	}
+}
@@ -0,0 +1,43 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+const RequestIDMetadataKey = "x-request-id"
+
+// NewID returns a random hex identifier for requests and streams.
+func NewID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+
+	return hex.EncodeToString(b)
+}
+
+type requestIDKey struct{}
+
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+type streamIDKey struct{}
+
+// WithStreamID stores the server generated id of a single stream, unlike the
+// request id it is never taken from the client.
+func WithStreamID(ctx context.Context, streamID string) context.Context {
+	return context.WithValue(ctx, streamIDKey{}, streamID)
+}
+
+func StreamIDFromContext(ctx context.Context) string {
+	streamID, _ := ctx.Value(streamIDKey{}).(string)
+	return streamID
+}
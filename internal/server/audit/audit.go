@@ -0,0 +1,53 @@
+package audit
+
+import (
+	"context"
+	"time"
+)
+
+type EventType string
+
+const (
+	StreamOpened     EventType = "stream_opened"
+	StreamClosed     EventType = "stream_closed"
+	AuthFailure      EventType = "auth_failure"
+	PermissionDenied EventType = "permission_denied"
+	AdminAction      EventType = "admin_action"
+	RoomJoin         EventType = "room_join"
+)
+
+// Event is a security relevant fact, the sink adds ordering and hashing.
+type Event struct {
+	Time            time.Time         `json:"time"`
+	Type            EventType         `json:"type"`
+	Principal       string            `json:"principal,omitempty"`
+	Peer            string            `json:"peer,omitempty"`
+	CertFingerprint string            `json:"cert_fingerprint,omitempty"`
+	RequestID       string            `json:"request_id,omitempty"`
+	Method          string            `json:"method,omitempty"`
+	Room            string            `json:"room,omitempty"`
+	Reason          string            `json:"reason,omitempty"`
+	Details         map[string]string `json:"details,omitempty"`
+}
+
+// Sink persists audit events. Implementations must be safe for concurrent use.
+type Sink interface {
+	Record(ctx context.Context, event Event) error
+	Close() error
+}
+
+// Checker is implemented by sinks that can tell when they stopped persisting
+// events, e.g. for a health check.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+type nopSink struct{}
+
+// NopSink discards every event, used when auditing is disabled.
+func NopSink() Sink {
+	return nopSink{}
+}
+
+func (nopSink) Record(context.Context, Event) error { return nil }
+func (nopSink) Close() error                        { return nil }
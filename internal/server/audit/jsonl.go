@@ -0,0 +1,154 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Record is a single line of the audit file. Hash covers the previous hash
+// and the record itself, so editing or removing a line breaks the chain.
+type Record struct {
+	Seq      uint64 `json:"seq"`
+	PrevHash string `json:"prev_hash"`
+	Hash     string `json:"hash"`
+	Event
+}
+
+// JSONLFile is an append-only JSON lines sink with hash chaining.
+type JSONLFile struct {
+	mu       sync.Mutex
+	file     *os.File
+	seq      uint64
+	lastHash string
+	// err is the last failed write, cleared by a successful one
+	err error
+}
+
+// OpenJSONLFile opens or creates the audit file and continues the existing
+// chain, it fails when the existing chain doesn't verify.
+func OpenJSONLFile(path string) (*JSONLFile, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	last, err := Verify(file)
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("audit log %s: %w", path, err)
+	}
+
+	return &JSONLFile{file: file, seq: last.Seq, lastHash: last.Hash}, nil
+}
+
+func (f *JSONLFile) Record(_ context.Context, event Event) error {
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	record := Record{Seq: f.seq + 1, PrevHash: f.lastHash, Event: event}
+	hash, err := recordHash(record)
+	if err != nil {
+		return err
+	}
+	record.Hash = hash
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err = f.file.Write(append(line, '\n')); err != nil {
+		f.err = err
+		return err
+	}
+	f.err = nil
+
+	f.seq = record.Seq
+	f.lastHash = record.Hash
+
+	return nil
+}
+
+// Check fails while the last event could not be written or the file cannot be
+// synced to the disk.
+func (f *JSONLFile) Check(context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return fmt.Errorf("audit log write failed: %w", f.err)
+	}
+	if err := f.file.Sync(); err != nil {
+		return fmt.Errorf("audit log sync failed: %w", err)
+	}
+
+	return nil
+}
+
+func (f *JSONLFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.file.Sync(); err != nil {
+		_ = f.file.Close()
+		return err
+	}
+
+	return f.file.Close()
+}
+
+// Verify reads a whole audit log, checks the hash chain and returns the last
+// record, a zero record for an empty log.
+func Verify(r io.Reader) (Record, error) {
+	var last Record
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for scanner.Scan() {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return last, fmt.Errorf("record after seq %d: %w", last.Seq, err)
+		}
+
+		if record.Seq != last.Seq+1 || record.PrevHash != last.Hash {
+			return last, fmt.Errorf("record %d: %w", record.Seq, ErrBrokenChain)
+		}
+
+		hash, err := recordHash(record)
+		if err != nil {
+			return last, err
+		}
+		if hash != record.Hash {
+			return last, fmt.Errorf("record %d: %w", record.Seq, ErrBrokenChain)
+		}
+
+		last = record
+	}
+
+	return last, scanner.Err()
+}
+
+var ErrBrokenChain = errors.New("audit hash chain is broken")
+
+func recordHash(record Record) (string, error) {
+	record.Hash = ""
+	data, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(append([]byte(record.PrevHash), data...))
+	return hex.EncodeToString(sum[:]), nil
+}
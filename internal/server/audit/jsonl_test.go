@@ -0,0 +1,32 @@
+package audit
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestJSONLFileCheck(t *testing.T) {
+	sink, err := OpenJSONLFile(filepath.Join(t.TempDir(), "audit.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	ctx := context.Background()
+	if err = sink.Record(ctx, Event{Type: StreamOpened, Principal: "alice"}); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	if err = sink.Check(ctx); err != nil {
+		t.Fatalf("healthy sink: %v", err)
+	}
+
+	// Закрытый файл имитирует отказ хранилища
+	_ = sink.file.Close()
+	if err = sink.Record(ctx, Event{Type: StreamClosed, Principal: "alice"}); err == nil {
+		t.Fatal("record to a closed file succeeded")
+	}
+	if err = sink.Check(ctx); err == nil {
+		t.Fatal("check passed after a failed write")
+	}
+}
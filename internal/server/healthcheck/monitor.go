@@ -0,0 +1,130 @@
+package healthcheck
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Check reports a degraded subsystem by returning an error.
+type Check func(ctx context.Context) error
+
+type check struct {
+	name     string
+	fn       Check
+	services []string
+}
+
+// Monitor periodically runs subsystem checks and publishes the result through
+// the standard grpc.health.v1 service. The overall status ("") is SERVING only
+// when every check passes, a named service is SERVING when the checks it
+// depends on pass.
+type Monitor struct {
+	server   *health.Server
+	services []string
+	interval time.Duration
+	timeout  time.Duration
+
+	mu           sync.Mutex
+	checks       []check
+	shuttingDown bool
+}
+
+func NewMonitor(server *health.Server, interval time.Duration, services ...string) *Monitor {
+	return &Monitor{
+		server:   server,
+		services: services,
+		interval: interval,
+		timeout:  5 * time.Second,
+	}
+}
+
+// AddCheck registers a check affecting the given services, or every
+// registered service when none is given.
+func (m *Monitor) AddCheck(name string, fn Check, services ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(services) == 0 {
+		services = m.services
+	}
+	m.checks = append(m.checks, check{name: name, fn: fn, services: services})
+}
+
+// Run evaluates the checks until ctx is done.
+func (m *Monitor) Run(ctx context.Context) {
+	m.evaluate(ctx)
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.evaluate(ctx)
+		}
+	}
+}
+
+// Shutdown flips every service to NOT_SERVING and ignores further checks.
+func (m *Monitor) Shutdown() {
+	m.mu.Lock()
+	m.shuttingDown = true
+	m.mu.Unlock()
+
+	m.server.Shutdown()
+}
+
+func (m *Monitor) evaluate(ctx context.Context) {
+	m.mu.Lock()
+	checks := append([]check(nil), m.checks...)
+	m.mu.Unlock()
+
+	healthy := make(map[string]bool, len(m.services))
+	for _, service := range m.services {
+		healthy[service] = true
+	}
+	overall := true
+
+	for _, c := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, m.timeout)
+		err := c.fn(checkCtx)
+		cancel()
+
+		if err == nil {
+			continue
+		}
+
+		slog.With("check", c.name, "error", err).Warn("health check failed")
+		overall = false
+		for _, service := range c.services {
+			healthy[service] = false
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.shuttingDown {
+		return
+	}
+
+	m.server.SetServingStatus("", servingStatus(overall))
+	for service, ok := range healthy {
+		m.server.SetServingStatus(service, servingStatus(ok))
+	}
+}
+
+func servingStatus(ok bool) healthpb.HealthCheckResponse_ServingStatus {
+	if ok {
+		return healthpb.HealthCheckResponse_SERVING
+	}
+
+	return healthpb.HealthCheckResponse_NOT_SERVING
+}
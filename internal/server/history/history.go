@@ -0,0 +1,139 @@
+package history
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "grpc-streaming/streaming/grpc"
+)
+
+// Entry is a message published to a room.
+type Entry struct {
+	Time      time.Time
+	Room      string
+	Principal string
+	Type      pb.Message_Type
+	Body      string
+	Metadata  map[string]string
+}
+
+// Message converts the entry back to the message delivered to the room.
+func (e Entry) Message() *pb.Message {
+	return &pb.Message{
+		Type:     e.Type,
+		Body:     e.Body,
+		Sender:   e.Principal,
+		SentAt:   timestamppb.New(e.Time),
+		Room:     e.Room,
+		Metadata: e.Metadata,
+	}
+}
+
+// Store keeps the last messages of every room in memory. It is meant for
+// inspection and export by administrators, not for durable storage: the
+// history is lost on restart.
+type Store struct {
+	size int
+
+	mu    sync.RWMutex
+	rooms map[string]*ring
+}
+
+// ring is a fixed size buffer overwriting its oldest entry.
+type ring struct {
+	entries []Entry
+	next    int
+	full    bool
+}
+
+// New returns a store keeping up to size entries per room, a size of 0
+// disables the history.
+func New(size int) *Store {
+	return &Store{
+		size:  size,
+		rooms: make(map[string]*ring),
+	}
+}
+
+func (s *Store) Enabled() bool {
+	return s.size > 0
+}
+
+func (s *Store) Append(entry Entry) {
+	if !s.Enabled() {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.rooms[entry.Room]
+	if !ok {
+		r = &ring{entries: make([]Entry, s.size)}
+		s.rooms[entry.Room] = r
+	}
+
+	r.entries[r.next] = entry
+	r.next = (r.next + 1) % s.size
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// Entries returns the room history newer than since, oldest first.
+func (s *Store) Entries(room string, since time.Time) []Entry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, ok := s.rooms[room]
+	if !ok {
+		return nil
+	}
+
+	ordered := r.entries[:r.next]
+	if r.full {
+		ordered = append(append([]Entry(nil), r.entries[r.next:]...), r.entries[:r.next]...)
+	}
+
+	var entries []Entry
+	for _, entry := range ordered {
+		if entry.Time.After(since) {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+// Len returns the number of stored entries per room.
+func (s *Store) Len() map[string]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	lengths := make(map[string]int, len(s.rooms))
+	for room, r := range s.rooms {
+		lengths[room] = r.next
+		if r.full {
+			lengths[room] = s.size
+		}
+	}
+
+	return lengths
+}
+
+// Rooms returns the rooms with stored history, sorted by name.
+func (s *Store) Rooms() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rooms := make([]string, 0, len(s.rooms))
+	for room := range s.rooms {
+		rooms = append(rooms, room)
+	}
+	sort.Strings(rooms)
+
+	return rooms
+}
@@ -0,0 +1,240 @@
+package hub
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"grpc-streaming/internal/logging"
+	pb "grpc-streaming/streaming/grpc"
+)
+
+var ErrSlowConsumer = status.Error(codes.ResourceExhausted, "slow consumer: outbound queue is full")
+
+// Hub fans out messages to every subscriber of a room. Publishing never
+// blocks: each subscriber owns a bounded queue and the hub policy decides
+// what to do when it overflows.
+type Hub struct {
+	queueSize int
+	policy    Policy
+
+	mu    sync.RWMutex
+	rooms map[string]map[*Subscriber]struct{}
+
+	dropped      atomic.Uint64
+	disconnected atomic.Uint64
+}
+
+// Stats is a point-in-time snapshot of the hub state.
+type Stats struct {
+	Rooms         int
+	Subscribers   int
+	QueueDepth    int
+	MaxQueueDepth int
+	Dropped       uint64
+	Disconnected  uint64
+}
+
+func New(queueSize int, policy Policy) *Hub {
+	if queueSize < 1 {
+		queueSize = 1
+	}
+
+	return &Hub{
+		queueSize: queueSize,
+		policy:    policy,
+		rooms:     make(map[string]map[*Subscriber]struct{}),
+	}
+}
+
+// Subscribe attaches a new subscriber to the room, the request-scoped logger
+// of ctx is used for the subscriber events.
+func (h *Hub) Subscribe(ctx context.Context, room string, client Client) *Subscriber {
+	sub := &Subscriber{
+		room:    room,
+		client:  client,
+		started: time.Now(),
+		logger:  logging.FromContext(ctx),
+		queue:   make(chan *pb.Message, h.queueSize),
+		done:    make(chan struct{}),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	subs, ok := h.rooms[room]
+	if !ok {
+		subs = make(map[*Subscriber]struct{})
+		h.rooms[room] = subs
+	}
+	subs[sub] = struct{}{}
+
+	return sub
+}
+
+func (h *Hub) Unsubscribe(sub *Subscriber) {
+	h.mu.Lock()
+	h.remove(sub)
+	h.mu.Unlock()
+
+	sub.close(nil)
+}
+
+// Publish fans the message out to the room and returns the number of
+// subscribers it was offered to.
+func (h *Hub) Publish(room string, msg *pb.Message) int {
+	var slow []*Subscriber
+
+	h.mu.RLock()
+	subs := h.rooms[room]
+	for sub := range subs {
+		if !sub.enqueue(msg, h.policy) {
+			slow = append(slow, sub)
+		}
+	}
+	recipients := len(subs)
+	h.mu.RUnlock()
+
+	for _, sub := range slow {
+		h.onOverflow(sub)
+	}
+
+	return recipients
+}
+
+// PublishAll fans the message out to every room.
+func (h *Hub) PublishAll(msg *pb.Message) int {
+	recipients := 0
+	for _, room := range h.Rooms() {
+		recipients += h.Publish(room, msg)
+	}
+
+	return recipients
+}
+
+// Rooms returns the names of the rooms with at least one subscriber.
+func (h *Hub) Rooms() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	rooms := make([]string, 0, len(h.rooms))
+	for room := range h.rooms {
+		rooms = append(rooms, room)
+	}
+	sort.Strings(rooms)
+
+	return rooms
+}
+
+// Subscribers snapshots the subscribers of a room, or of every room when room
+// is empty, oldest first.
+func (h *Hub) Subscribers(room string) []SubscriberInfo {
+	h.mu.RLock()
+	var infos []SubscriberInfo
+	for name, subs := range h.rooms {
+		if room != "" && name != room {
+			continue
+		}
+		for sub := range subs {
+			infos = append(infos, sub.Info())
+		}
+	}
+	h.mu.RUnlock()
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Started.Before(infos[j].Started)
+	})
+
+	return infos
+}
+
+// Disconnect removes the subscriber of the given stream, the stream ends with
+// err. It reports whether the stream was found.
+func (h *Hub) Disconnect(streamID string, err error) bool {
+	h.mu.Lock()
+	var found *Subscriber
+	for _, subs := range h.rooms {
+		for sub := range subs {
+			if sub.client.StreamID == streamID {
+				found = sub
+			}
+		}
+	}
+	if found != nil {
+		h.remove(found)
+	}
+	h.mu.Unlock()
+
+	if found == nil {
+		return false
+	}
+
+	found.logger.Warn("subscriber disconnected by an administrator")
+	found.close(err)
+
+	return true
+}
+
+func (h *Hub) Stats() Stats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	stats := Stats{
+		Rooms:        len(h.rooms),
+		Dropped:      h.dropped.Load(),
+		Disconnected: h.disconnected.Load(),
+	}
+	for _, subs := range h.rooms {
+		for sub := range subs {
+			depth := len(sub.queue)
+			stats.Subscribers++
+			stats.QueueDepth += depth
+			stats.MaxQueueDepth = max(stats.MaxQueueDepth, depth)
+		}
+	}
+
+	return stats
+}
+
+func (h *Hub) onOverflow(sub *Subscriber) {
+	h.dropped.Add(1)
+
+	if h.policy != Disconnect {
+		sub.logger.With("room", sub.room, "policy", h.policy, "dropped", sub.dropped.Load()).
+			Debug("subscriber queue is full, message dropped")
+		return
+	}
+
+	h.mu.Lock()
+	removed := h.remove(sub)
+	h.mu.Unlock()
+
+	if removed {
+		h.disconnected.Add(1)
+		sub.logger.With("room", sub.room).Warn("disconnecting slow consumer")
+		sub.close(ErrSlowConsumer)
+	}
+}
+
+// remove must be called with h.mu held.
+func (h *Hub) remove(sub *Subscriber) bool {
+	subs, ok := h.rooms[sub.room]
+	if !ok {
+		return false
+	}
+	if _, ok = subs[sub]; !ok {
+		return false
+	}
+
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(h.rooms, sub.room)
+	}
+
+	return true
+}
@@ -0,0 +1,41 @@
+package hub
+
+import "fmt"
+
+// Policy decides what happens when a subscriber's outbound queue is full.
+type Policy int
+
+const (
+	// DropOldest evicts the oldest queued message to make room for the new one.
+	DropOldest Policy = iota
+	// DropNewest discards the incoming message and keeps the queue as is.
+	DropNewest
+	// Disconnect closes the slow subscriber with codes.ResourceExhausted.
+	Disconnect
+)
+
+func ParsePolicy(s string) (Policy, error) {
+	switch s {
+	case "drop-oldest":
+		return DropOldest, nil
+	case "drop-newest":
+		return DropNewest, nil
+	case "disconnect":
+		return Disconnect, nil
+	}
+
+	return 0, fmt.Errorf("unknown slow consumer policy %q", s)
+}
+
+func (p Policy) String() string {
+	switch p {
+	case DropOldest:
+		return "drop-oldest"
+	case DropNewest:
+		return "drop-newest"
+	case Disconnect:
+		return "disconnect"
+	}
+
+	return fmt.Sprintf("Policy(%d)", int(p))
+}
@@ -0,0 +1,27 @@
+package hub
+
+import (
+	"context"
+
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	DefaultRoom     = "general"
+	RoomMetadataKey = "room"
+)
+
+// RoomFromContext returns the room requested by the client in the incoming
+// metadata or DefaultRoom if none was given.
+func RoomFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return DefaultRoom
+	}
+
+	if values := md.Get(RoomMetadataKey); len(values) > 0 && values[0] != "" {
+		return values[0]
+	}
+
+	return DefaultRoom
+}
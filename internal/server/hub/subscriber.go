@@ -0,0 +1,117 @@
+package hub
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pb "grpc-streaming/streaming/grpc"
+)
+
+// Client identifies the stream behind a subscriber for the admin API.
+type Client struct {
+	StreamID  string
+	Principal string
+	Peer      string
+}
+
+// SubscriberInfo is a point-in-time snapshot of a subscriber.
+type SubscriberInfo struct {
+	Client
+	Room       string
+	Started    time.Time
+	QueueDepth int
+	Dropped    uint64
+}
+
+// Subscriber is a single stream attached to a hub room.
+type Subscriber struct {
+	room    string
+	client  Client
+	started time.Time
+	queue   chan *pb.Message
+	logger  *slog.Logger
+
+	mu        sync.Mutex
+	done      chan struct{}
+	closeOnce sync.Once
+	err       error
+
+	dropped atomic.Uint64
+}
+
+func (s *Subscriber) Room() string {
+	return s.room
+}
+
+// Messages returns the outbound queue the stream has to drain.
+func (s *Subscriber) Messages() <-chan *pb.Message {
+	return s.queue
+}
+
+// Done is closed once the subscriber was removed from the hub.
+func (s *Subscriber) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err reports why the subscriber was closed, nil for a regular unsubscribe.
+func (s *Subscriber) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
+}
+
+func (s *Subscriber) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+func (s *Subscriber) Info() SubscriberInfo {
+	return SubscriberInfo{
+		Client:     s.client,
+		Room:       s.room,
+		Started:    s.started,
+		QueueDepth: len(s.queue),
+		Dropped:    s.dropped.Load(),
+	}
+}
+
+// enqueue never blocks, it returns false when the message (or an older one)
+// had to be dropped because the queue is full.
+func (s *Subscriber) enqueue(msg *pb.Message, policy Policy) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case s.queue <- msg:
+		return true
+	default:
+	}
+
+	s.dropped.Add(1)
+	if policy != DropOldest {
+		return false
+	}
+
+	// Освобождаем место, выкидывая самое старое сообщение
+	select {
+	case <-s.queue:
+	default:
+	}
+	select {
+	case s.queue <- msg:
+	default:
+	}
+
+	return false
+}
+
+func (s *Subscriber) close(err error) {
+	s.closeOnce.Do(func() {
+		s.mu.Lock()
+		s.err = err
+		s.mu.Unlock()
+		close(s.done)
+	})
+}
@@ -0,0 +1,79 @@
+package interceptors
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	"grpc-streaming/internal/logging"
+	"grpc-streaming/internal/server/audit"
+)
+
+// AuditServerInterceptor writes stream lifecycle events to the audit sink and
+// implements Observer to record authentication failures and denials.
+type AuditServerInterceptor struct {
+	sink audit.Sink
+}
+
+func NewAuditServerInterceptor(sink audit.Sink) *AuditServerInterceptor {
+	return &AuditServerInterceptor{sink: sink}
+}
+
+func (interceptor *AuditServerInterceptor) Stream() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		stream grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		ctx := stream.Context()
+		started := time.Now()
+
+		Audit(ctx, interceptor.sink, AuditEvent(ctx, audit.StreamOpened, info.FullMethod))
+
+		err := handler(srv, stream)
+
+		event := AuditEvent(ctx, audit.StreamClosed, info.FullMethod)
+		event.Reason = status.Code(err).String()
+		event.Details = map[string]string{"duration": time.Since(started).Round(time.Millisecond).String()}
+		Audit(ctx, interceptor.sink, event)
+
+		return err
+	}
+}
+
+func (interceptor *AuditServerInterceptor) AuthFailed(ctx context.Context, method, reason string) {
+	eventType := audit.AuthFailure
+	if reason == "permission_denied" {
+		eventType = audit.PermissionDenied
+	}
+
+	event := AuditEvent(ctx, eventType, method)
+	event.Reason = reason
+	Audit(ctx, interceptor.sink, event)
+}
+
+func (interceptor *AuditServerInterceptor) RateLimited(context.Context, string, string)    {}
+func (interceptor *AuditServerInterceptor) StreamRejected(context.Context, string, string) {}
+
+// AuditEvent prefills an event with the caller details found in ctx.
+func AuditEvent(ctx context.Context, eventType audit.EventType, method string) audit.Event {
+	return audit.Event{
+		Time:            time.Now().UTC(),
+		Type:            eventType,
+		Principal:       PrincipalFromContext(ctx),
+		Peer:            PeerIP(ctx),
+		CertFingerprint: CertFingerprint(ctx),
+		RequestID:       logging.RequestIDFromContext(ctx),
+		Method:          method,
+	}
+}
+
+// Audit records the event and logs a failure instead of failing the RPC.
+func Audit(ctx context.Context, sink audit.Sink, event audit.Event) {
+	if err := sink.Record(ctx, event); err != nil {
+		logging.FromContext(ctx).With("error", err, "event", event.Type).Error("failed to write audit record")
+	}
+}
@@ -2,20 +2,66 @@ package interceptors
 
 import (
 	"context"
-	"log/slog"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
+
+	"grpc-streaming/internal/logging"
 )
 
+// defaultRole is granted to any token when no tokens file is configured.
+const defaultRole = "user"
+
 type AuthServerInterceptor struct {
 	accessibleRoles []string
+	methodRoles     map[string][]string
+	publicMethods   map[string]bool
+	tokens          map[string]Identity
+	observer        Observer
 }
 
 func NewAuthServerInterceptor(accessibleRoles []string) *AuthServerInterceptor {
-	return &AuthServerInterceptor{accessibleRoles}
+	return &AuthServerInterceptor{
+		accessibleRoles: accessibleRoles,
+		methodRoles:     make(map[string][]string),
+		publicMethods:   make(map[string]bool),
+		observer:        nopObserver{},
+	}
+}
+
+func (interceptor *AuthServerInterceptor) WithObserver(observer Observer) *AuthServerInterceptor {
+	interceptor.observer = observer
+	return interceptor
+}
+
+// WithTokens restricts access to the known tokens. Without it every token is
+// accepted and gets the default "user" role.
+func (interceptor *AuthServerInterceptor) WithTokens(tokens map[string]Identity) *AuthServerInterceptor {
+	interceptor.tokens = tokens
+	return interceptor
+}
+
+// WithMethodRoles overrides the accessible roles for a single full method name.
+func (interceptor *AuthServerInterceptor) WithMethodRoles(method string, roles ...string) *AuthServerInterceptor {
+	interceptor.methodRoles[method] = roles
+	return interceptor
+}
+
+// WithPublicMethods lets the given full method names through without a token,
+// e.g. health checks coming from an orchestrator.
+func (interceptor *AuthServerInterceptor) WithPublicMethods(methods ...string) *AuthServerInterceptor {
+	for _, method := range methods {
+		interceptor.publicMethods[method] = true
+	}
+
+	return interceptor
 }
 
 func (interceptor *AuthServerInterceptor) Unary() grpc.UnaryServerInterceptor {
@@ -25,14 +71,16 @@ func (interceptor *AuthServerInterceptor) Unary() grpc.UnaryServerInterceptor {
 		info *grpc.UnaryServerInfo,
 		handler grpc.UnaryHandler,
 	) (interface{}, error) {
-		slog.With("method", info.FullMethod).Debug("--> unary auth server interceptor")
+		logging.FromContext(ctx).Debug("--> unary auth server interceptor")
 
-		err := interceptor.authorize(ctx)
+		principal, reason, err := interceptor.authorize(ctx, info.FullMethod)
 		if err != nil {
-			slog.With("error", err).Error("Unauthorized")
+			logging.FromContext(ctx).With("error", err, "reason", reason).Error("Unauthorized")
+			interceptor.observer.AuthFailed(ctx, info.FullMethod, reason)
 			return nil, err
 		}
 
+		ctx = logging.With(withPrincipal(ctx, principal), "principal", principal)
 		return handler(ctx, req)
 	}
 }
@@ -44,37 +92,113 @@ func (interceptor *AuthServerInterceptor) Stream() grpc.StreamServerInterceptor
 		info *grpc.StreamServerInfo,
 		handler grpc.StreamHandler,
 	) error {
-		slog.With("method", info.FullMethod).Debug("--> stream auth server interceptor triggered")
-		// TODO: implement streaming authorization
-		return handler(srv, stream)
+		logging.FromContext(stream.Context()).Debug("--> stream auth server interceptor triggered")
+
+		principal, reason, err := interceptor.authorize(stream.Context(), info.FullMethod)
+		if err != nil {
+			logging.FromContext(stream.Context()).With("error", err, "reason", reason).Error("Unauthorized")
+			interceptor.observer.AuthFailed(stream.Context(), info.FullMethod, reason)
+			return err
+		}
+
+		ctx := logging.With(withPrincipal(stream.Context(), principal), "principal", principal)
+		return handler(srv, wrapServerStream(ctx, stream))
+	}
+}
+
+// Authorize applies the checks of the interceptors to method for requests
+// that do not come through gRPC, e.g. to the HTTP gateway, see HTTPContext.
+// It returns ctx with the caller principal.
+func (interceptor *AuthServerInterceptor) Authorize(ctx context.Context, method string) (context.Context, error) {
+	principal, reason, err := interceptor.authorize(ctx, method)
+	if err != nil {
+		logging.FromContext(ctx).With("error", err, "reason", reason).Error("Unauthorized")
+		interceptor.observer.AuthFailed(ctx, method, reason)
+		return nil, err
 	}
+
+	return logging.With(withPrincipal(ctx, principal), "principal", principal), nil
 }
 
-func (interceptor *AuthServerInterceptor) authorize(ctx context.Context) error {
-	accessibleRoles := interceptor.accessibleRoles
-	if len(accessibleRoles) == 0 {
+// authorize returns the caller principal, or a short failure reason and the
+// status error to return.
+func (interceptor *AuthServerInterceptor) authorize(ctx context.Context, method string) (string, string, error) {
+	accessibleRoles, ok := interceptor.methodRoles[method]
+	if !ok {
+		accessibleRoles = interceptor.accessibleRoles
+	}
+	if len(interceptor.accessibleRoles) == 0 || interceptor.publicMethods[method] {
 		// everyone can access
-		return nil
+		return certPrincipal(ctx), "", nil
 	}
 
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
-		return status.Errorf(codes.Unauthenticated, "metadata is not provided")
+		return "", "no_metadata", status.Errorf(codes.Unauthenticated, "metadata is not provided")
 	}
 
 	values := md["authorization"]
 	if len(values) == 0 {
-		return status.Errorf(codes.Unauthenticated, "authorization token is not provided")
+		return "", "no_token", status.Errorf(codes.Unauthenticated, "authorization token is not provided")
+	}
+
+	accessToken := strings.TrimPrefix(values[0], "Bearer ")
+	identity, isValid := interceptor.validateToken(accessToken)
+	if !isValid {
+		return "", "invalid_token", status.Errorf(codes.Unauthenticated, "access token is invalid")
+	}
+
+	if !identity.HasAnyRole(accessibleRoles) {
+		return "", "permission_denied", status.Error(codes.PermissionDenied, "no permission to access this RPC")
+	}
+
+	principal := certPrincipal(ctx)
+	switch {
+	case principal == "":
+		return identity.Principal, "", nil
+	case interceptor.tokens == nil:
+		// Анонимный токен не называет участника, имя дает сертификат
+		return principal, "", nil
+	case principal != identity.Principal:
+		// Роли токена не переносятся на чужой сертификат
+		return "", "principal_mismatch", status.Error(codes.PermissionDenied, "client certificate and access token belong to different principals")
+	}
+
+	return principal, "", nil
+}
+
+func (interceptor *AuthServerInterceptor) validateToken(accessToken string) (Identity, bool) {
+	if accessToken == "" {
+		return Identity{}, false
 	}
 
-	accessToken := values[0]
-	if isValid := validateToken(accessToken); isValid {
-		return nil
+	if interceptor.tokens == nil {
+		// Токен не может быть именем: его видят участники комнаты, логи и аудит
+		return Identity{Principal: anonymousPrincipal(accessToken), Roles: []string{defaultRole}}, true
 	}
 
-	return status.Error(codes.PermissionDenied, "no permission to access this RPC")
+	identity, ok := interceptor.tokens[accessToken]
+	return identity, ok
+}
+
+// anonymousPrincipal names the holder of a token when there is no tokens file
+// without revealing the token, the same token always gets the same name.
+func anonymousPrincipal(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+	return "anon-" + hex.EncodeToString(sum[:6])
 }
 
-func validateToken(_ string) bool {
-	return true
+// certPrincipal returns the common name of a verified client certificate.
+func certPrincipal(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return ""
+	}
+
+	return tlsInfo.State.VerifiedChains[0][0].Subject.CommonName
 }
@@ -0,0 +1,131 @@
+package interceptors
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"grpc-streaming/internal/logging"
+)
+
+const secretToken = "s3cr3t-token"
+
+func authorizedContext(t *testing.T, interceptor *AuthServerInterceptor, logs *bytes.Buffer) context.Context {
+	t.Helper()
+
+	logger := slog.New(slog.NewTextHandler(logs, &slog.HandlerOptions{
+		ReplaceAttr: logging.NewRedactor(logging.DefaultRedactFields).ReplaceAttr,
+	}))
+	ctx := logging.WithLogger(context.Background(), logger)
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+secretToken))
+
+	var handled context.Context
+	_, err := interceptor.Unary()(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/streaming.Chat/Who"},
+		func(ctx context.Context, _ interface{}) (interface{}, error) {
+			handled = ctx
+			logging.FromContext(ctx).Info("handled")
+			return nil, nil
+		})
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+
+	return handled
+}
+
+func TestAnonymousPrincipalHidesToken(t *testing.T) {
+	var logs bytes.Buffer
+	ctx := authorizedContext(t, NewAuthServerInterceptor([]string{"user"}), &logs)
+
+	principal := PrincipalFromContext(ctx)
+	if principal == "" || strings.Contains(principal, secretToken) {
+		t.Errorf("principal %q must be set and must not contain the token", principal)
+	}
+	if again := anonymousPrincipal(secretToken); again != principal {
+		t.Errorf("principal is not stable: %q != %q", again, principal)
+	}
+	if anonymousPrincipal("other-token") == principal {
+		t.Error("different tokens got the same principal")
+	}
+
+	if !strings.Contains(logs.String(), "principal="+principal) {
+		t.Errorf("principal is not logged: %s", logs.String())
+	}
+	if strings.Contains(logs.String(), secretToken) {
+		t.Errorf("token leaked into the logs: %s", logs.String())
+	}
+}
+
+func TestKnownTokenPrincipal(t *testing.T) {
+	var logs bytes.Buffer
+	interceptor := NewAuthServerInterceptor([]string{"user"}).
+		WithTokens(map[string]Identity{secretToken: {Principal: "alice", Roles: []string{"user"}}})
+	ctx := authorizedContext(t, interceptor, &logs)
+
+	if principal := PrincipalFromContext(ctx); principal != "alice" {
+		t.Errorf("principal = %q, want alice", principal)
+	}
+	if strings.Contains(logs.String(), secretToken) {
+		t.Errorf("token leaked into the logs: %s", logs.String())
+	}
+}
+
+// certContext is ctx of a mutual TLS peer whose verified certificate has the
+// common name cn.
+func certContext(ctx context.Context, cn string) context.Context {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: cn}}
+	return peer.NewContext(ctx, &peer.Peer{AuthInfo: credentials.TLSInfo{
+		State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}},
+	}})
+}
+
+func TestCertAndTokenPrincipal(t *testing.T) {
+	interceptor := NewAuthServerInterceptor([]string{"user"}).
+		WithTokens(map[string]Identity{secretToken: {Principal: "alice", Roles: []string{"user", "admin"}}})
+
+	tests := []struct {
+		name      string
+		cn        string
+		tokens    bool
+		want      string
+		wantError codes.Code
+	}{
+		{"token only", "", true, "alice", codes.OK},
+		{"cert of the token principal", "alice", true, "alice", codes.OK},
+		{"cert of another principal", "mallory", true, "", codes.PermissionDenied},
+		{"cert with an anonymous token", "mallory", false, "mallory", codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := interceptor
+			if !tt.tokens {
+				i = NewAuthServerInterceptor([]string{"user"})
+			}
+
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+secretToken))
+			if tt.cn != "" {
+				ctx = certContext(ctx, tt.cn)
+			}
+
+			principal, _, err := i.authorize(ctx, "/streaming.Chat/Who")
+			if status.Code(err) != tt.wantError {
+				t.Fatalf("err = %v, want %s", err, tt.wantError)
+			}
+			if principal != tt.want {
+				t.Errorf("principal = %q, want %q", principal, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package interceptors
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+type principalKey struct{}
+
+func withPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the caller identity set by the auth interceptor.
+func PrincipalFromContext(ctx context.Context) string {
+	principal, _ := ctx.Value(principalKey{}).(string)
+	return principal
+}
+
+// PeerIP returns the remote IP address of the caller without the port.
+func PeerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+
+	return host
+}
+
+// PeerCertificate returns the leaf TLS certificate presented by the client.
+func PeerCertificate(ctx context.Context) *x509.Certificate {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.PeerCertificates) == 0 {
+		return nil
+	}
+
+	return tlsInfo.State.PeerCertificates[0]
+}
+
+// CertFingerprint returns the SHA-256 fingerprint of the client certificate.
+func CertFingerprint(ctx context.Context) string {
+	cert := PeerCertificate(ctx)
+	if cert == nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// serverStream overrides the context of the wrapped grpc.ServerStream so
+// values added by interceptors reach the handler.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func wrapServerStream(ctx context.Context, stream grpc.ServerStream) *serverStream {
+	return &serverStream{ServerStream: stream, ctx: ctx}
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,56 @@
+package interceptors
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"grpc-streaming/internal/logging"
+	"grpc-streaming/internal/server/hub"
+)
+
+// HTTPContext gives an HTTP request what gRPC and the request id interceptor
+// give an RPC, so the auth and rate limit checks work the same for it: the
+// authorization and x-request-id headers as incoming metadata, room as the
+// room metadata, the peer with its TLS state, the request and stream ids and
+// the request-scoped logger.
+func HTTPContext(r *http.Request, method, room string) context.Context {
+	md := metadata.MD{}
+	for key, values := range r.Header {
+		if key = strings.ToLower(key); key == "authorization" || key == logging.RequestIDMetadataKey {
+			md.Append(key, values...)
+		}
+	}
+	if room != "" {
+		md.Set(hub.RoomMetadataKey, room)
+	}
+	ctx := metadata.NewIncomingContext(r.Context(), md)
+
+	p := &peer.Peer{Addr: httpAddr(r.RemoteAddr)}
+	if r.TLS != nil {
+		p.AuthInfo = credentials.TLSInfo{State: *r.TLS}
+	}
+	ctx = peer.NewContext(ctx, p)
+
+	requestID := requestIDFromContext(ctx)
+	streamID := logging.NewID()
+	ctx = logging.WithStreamID(logging.WithRequestID(ctx, requestID), streamID)
+
+	return logging.WithLogger(ctx, slog.Default().With(
+		"request_id", requestID,
+		"stream_id", streamID,
+		"method", method,
+		"peer", PeerIP(ctx),
+	))
+}
+
+// httpAddr keeps the remote address of an HTTP request as a net.Addr.
+type httpAddr string
+
+func (a httpAddr) Network() string { return "tcp" }
+func (a httpAddr) String() string  { return string(a) }
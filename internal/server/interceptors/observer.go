@@ -0,0 +1,42 @@
+package interceptors
+
+import "context"
+
+// Observer receives the rejections made by the server interceptors, e.g. to
+// export them as metrics or write them to the audit log.
+type Observer interface {
+	AuthFailed(ctx context.Context, method, reason string)
+	RateLimited(ctx context.Context, method, scope string)
+	StreamRejected(ctx context.Context, method, reason string)
+}
+
+type nopObserver struct{}
+
+func (nopObserver) AuthFailed(context.Context, string, string)     {}
+func (nopObserver) RateLimited(context.Context, string, string)    {}
+func (nopObserver) StreamRejected(context.Context, string, string) {}
+
+type multiObserver []Observer
+
+// MultiObserver fans the events out to every given observer.
+func MultiObserver(observers ...Observer) Observer {
+	return multiObserver(observers)
+}
+
+func (m multiObserver) AuthFailed(ctx context.Context, method, reason string) {
+	for _, o := range m {
+		o.AuthFailed(ctx, method, reason)
+	}
+}
+
+func (m multiObserver) RateLimited(ctx context.Context, method, scope string) {
+	for _, o := range m {
+		o.RateLimited(ctx, method, scope)
+	}
+}
+
+func (m multiObserver) StreamRejected(ctx context.Context, method, reason string) {
+	for _, o := range m {
+		o.StreamRejected(ctx, method, reason)
+	}
+}
@@ -0,0 +1,155 @@
+package interceptors
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"grpc-streaming/internal/logging"
+	"grpc-streaming/internal/server/hub"
+	"grpc-streaming/internal/server/ratelimit"
+	pb "grpc-streaming/streaming/grpc"
+)
+
+type RateLimitConfig struct {
+	Principal ratelimit.Limit
+	IP        ratelimit.Limit
+	Room      ratelimit.Limit
+}
+
+type RateLimitServerInterceptor struct {
+	principal *ratelimit.Limiter
+	ip        *ratelimit.Limiter
+	room      *ratelimit.Limiter
+	observer  Observer
+}
+
+func NewRateLimitServerInterceptor(config RateLimitConfig) *RateLimitServerInterceptor {
+	return &RateLimitServerInterceptor{
+		principal: ratelimit.NewLimiter(config.Principal),
+		ip:        ratelimit.NewLimiter(config.IP),
+		room:      ratelimit.NewLimiter(config.Room),
+		observer:  nopObserver{},
+	}
+}
+
+func (interceptor *RateLimitServerInterceptor) WithObserver(observer Observer) *RateLimitServerInterceptor {
+	interceptor.observer = observer
+	return interceptor
+}
+
+func (interceptor *RateLimitServerInterceptor) Unary() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if err := interceptor.Allow(ctx, info.FullMethod, req); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func (interceptor *RateLimitServerInterceptor) Stream() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		stream grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		return handler(srv, &rateLimitedStream{
+			ServerStream: stream,
+			interceptor:  interceptor,
+			method:       info.FullMethod,
+		})
+	}
+}
+
+// Allow charges msg to the principal, IP and room of ctx and fails with
+// ResourceExhausted once a limit is exceeded. The interceptors call it for
+// every received message, the HTTP gateway for every posted one. Heartbeat
+// frames are free.
+func (interceptor *RateLimitServerInterceptor) Allow(ctx context.Context, method string, msg interface{}) error {
+	if m, ok := msg.(*pb.Message); ok && m.GetType() == pb.Message_HEARTBEAT {
+		return nil
+	}
+
+	size := 0
+	if m, ok := msg.(proto.Message); ok {
+		size = proto.Size(m)
+	}
+
+	scopes := []struct {
+		name    string
+		key     string
+		limiter *ratelimit.Limiter
+	}{
+		{"principal", PrincipalFromContext(ctx), interceptor.principal},
+		{"ip", PeerIP(ctx), interceptor.ip},
+		{"room", hub.RoomFromContext(ctx), interceptor.room},
+	}
+
+	for i, scope := range scopes {
+		if scope.key == "" {
+			continue
+		}
+
+		if ok, wait := scope.limiter.Allow(scope.key, size); !ok {
+			// Отклоненное сообщение не должно расходовать лимиты предыдущих областей
+			for _, charged := range scopes[:i] {
+				if charged.key != "" {
+					charged.limiter.Refund(charged.key, size)
+				}
+			}
+
+			logging.FromContext(ctx).With("scope", scope.name, "key", scope.key, "retryAfter", wait).
+				Warn("rate limit exceeded")
+			interceptor.observer.RateLimited(ctx, method, scope.name)
+			return rateLimitError(scope.name, scope.key, wait)
+		}
+	}
+
+	return nil
+}
+
+func rateLimitError(scope, key string, wait time.Duration) error {
+	st := status.New(codes.ResourceExhausted, fmt.Sprintf("rate limit exceeded for %s, retry in %s", scope, wait))
+
+	detailed, err := st.WithDetails(
+		&errdetails.RetryInfo{RetryDelay: durationpb.New(wait)},
+		&errdetails.QuotaFailure{Violations: []*errdetails.QuotaFailure_Violation{{
+			Subject:     scope + ":" + key,
+			Description: "message rate or byte rate limit exceeded",
+		}}},
+	)
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
+
+// rateLimitedStream checks the limits for every message received from the client.
+type rateLimitedStream struct {
+	grpc.ServerStream
+	interceptor *RateLimitServerInterceptor
+	method      string
+}
+
+func (s *rateLimitedStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	return s.interceptor.Allow(s.Context(), s.method, m)
+}
@@ -0,0 +1,66 @@
+package interceptors
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"grpc-streaming/internal/server/hub"
+	"grpc-streaming/internal/server/ratelimit"
+	pb "grpc-streaming/streaming/grpc"
+)
+
+func TestRateLimitRefundsOnRejection(t *testing.T) {
+	// Комната пропускает одно сообщение, участник три, пополнения за время теста нет
+	interceptor := NewRateLimitServerInterceptor(RateLimitConfig{
+		Principal: ratelimit.Limit{Messages: 0.001, Burst: 3000 * time.Second},
+		Room:      ratelimit.Limit{Messages: 0.001, Burst: 1000 * time.Second},
+	})
+
+	ctx := func(room string) context.Context {
+		ctx := withPrincipal(context.Background(), "alice")
+		return metadata.NewIncomingContext(ctx, metadata.Pairs(hub.RoomMetadataKey, room))
+	}
+
+	if err := interceptor.Allow(ctx("busy"), "/test", nil); err != nil {
+		t.Fatalf("first message: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		if err := interceptor.Allow(ctx("busy"), "/test", nil); status.Code(err) != codes.ResourceExhausted {
+			t.Fatalf("message %d to the busy room: %v, want ResourceExhausted", i, err)
+		}
+	}
+
+	// Отклоненные комнатой сообщения не списаны с участника
+	for i := 0; i < 2; i++ {
+		if err := interceptor.Allow(ctx("quiet"+string(rune('a'+i))), "/test", nil); err != nil {
+			t.Fatalf("message %d to another room: %v", i, err)
+		}
+	}
+	if err := interceptor.Allow(ctx("quietc"), "/test", nil); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("principal budget: %v, want ResourceExhausted", err)
+	}
+}
+
+func TestRateLimitSkipsHeartbeats(t *testing.T) {
+	interceptor := NewRateLimitServerInterceptor(RateLimitConfig{
+		Principal: ratelimit.Limit{Messages: 0.001, Burst: 1000 * time.Second},
+	})
+	ctx := withPrincipal(context.Background(), "alice")
+
+	for i := 0; i < 10; i++ {
+		if err := interceptor.Allow(ctx, "/test", &pb.Message{Type: pb.Message_HEARTBEAT}); err != nil {
+			t.Fatalf("heartbeat %d: %v", i, err)
+		}
+	}
+	if err := interceptor.Allow(ctx, "/test", &pb.Message{Body: "hi"}); err != nil {
+		t.Fatalf("message after heartbeats: %v", err)
+	}
+	if err := interceptor.Allow(ctx, "/test", &pb.Message{Body: "hi"}); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("second message: %v, want ResourceExhausted", err)
+	}
+}
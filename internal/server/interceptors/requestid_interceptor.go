@@ -0,0 +1,78 @@
+package interceptors
+
+import (
+	"context"
+	"log/slog"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"grpc-streaming/internal/logging"
+)
+
+// maxRequestIDLength bounds client supplied ids so they can't flood the logs.
+const maxRequestIDLength = 128
+
+// RequestIDServerInterceptor accepts or generates an x-request-id per RPC,
+// echoes it in the response header and puts a request-scoped logger into the
+// context. It has to be the first interceptor of the chain.
+type RequestIDServerInterceptor struct{}
+
+func NewRequestIDServerInterceptor() *RequestIDServerInterceptor {
+	return &RequestIDServerInterceptor{}
+}
+
+func (interceptor *RequestIDServerInterceptor) Unary() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		requestID := requestIDFromContext(ctx)
+		_ = grpc.SetHeader(ctx, metadata.Pairs(logging.RequestIDMetadataKey, requestID))
+
+		ctx = logging.WithLogger(logging.WithRequestID(ctx, requestID), slog.Default().With(
+			"request_id", requestID,
+			"method", info.FullMethod,
+			"peer", PeerIP(ctx),
+		))
+
+		return handler(ctx, req)
+	}
+}
+
+func (interceptor *RequestIDServerInterceptor) Stream() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		stream grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		ctx := stream.Context()
+		requestID := requestIDFromContext(ctx)
+		_ = stream.SetHeader(metadata.Pairs(logging.RequestIDMetadataKey, requestID))
+
+		streamID := logging.NewID()
+		ctx = logging.WithStreamID(logging.WithRequestID(ctx, requestID), streamID)
+		ctx = logging.WithLogger(ctx, slog.Default().With(
+			"request_id", requestID,
+			"stream_id", streamID,
+			"method", info.FullMethod,
+			"peer", PeerIP(ctx),
+		))
+
+		return handler(srv, wrapServerStream(ctx, stream))
+	}
+}
+
+func requestIDFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if ok {
+		if values := md.Get(logging.RequestIDMetadataKey); len(values) > 0 && values[0] != "" && len(values[0]) <= maxRequestIDLength {
+			return values[0]
+		}
+	}
+
+	return logging.NewID()
+}
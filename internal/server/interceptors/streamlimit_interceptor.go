@@ -0,0 +1,155 @@
+package interceptors
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"grpc-streaming/internal/logging"
+)
+
+type StreamLimitConfig struct {
+	// MaxPerPrincipal is the number of concurrent streams a single principal
+	// may keep open, 0 means unlimited.
+	MaxPerPrincipal int
+	// MaxPerIP is the number of concurrent streams allowed from one source IP.
+	MaxPerIP int
+}
+
+type StreamLimitStats struct {
+	Active   int
+	Rejected uint64
+	// Principals and IPs hold the number of currently open streams per key.
+	Principals map[string]int
+	IPs        map[string]int
+}
+
+type StreamLimitServerInterceptor struct {
+	config   StreamLimitConfig
+	observer Observer
+
+	mu         sync.Mutex
+	active     int
+	rejected   uint64
+	principals map[string]int
+	ips        map[string]int
+}
+
+func NewStreamLimitServerInterceptor(config StreamLimitConfig) *StreamLimitServerInterceptor {
+	return &StreamLimitServerInterceptor{
+		config:     config,
+		observer:   nopObserver{},
+		principals: make(map[string]int),
+		ips:        make(map[string]int),
+	}
+}
+
+func (interceptor *StreamLimitServerInterceptor) WithObserver(observer Observer) *StreamLimitServerInterceptor {
+	interceptor.observer = observer
+	return interceptor
+}
+
+func (interceptor *StreamLimitServerInterceptor) Stream() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		stream grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		release, err := interceptor.Admit(stream.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		return handler(srv, stream)
+	}
+}
+
+// Admit applies the limits to a stream of method that does not come through
+// gRPC, e.g. a stream of the HTTP gateway, see HTTPContext. The returned
+// release has to be called once the stream ends.
+func (interceptor *StreamLimitServerInterceptor) Admit(ctx context.Context, method string) (func(), error) {
+	principal := PrincipalFromContext(ctx)
+	ip := PeerIP(ctx)
+
+	if reason, err := interceptor.acquire(principal, ip); err != nil {
+		logging.FromContext(ctx).With("error", err).
+			Warn("stream limit exceeded")
+		interceptor.observer.StreamRejected(ctx, method, reason)
+		return nil, err
+	}
+
+	return func() {
+		interceptor.release(principal, ip)
+	}, nil
+}
+
+func (interceptor *StreamLimitServerInterceptor) Stats() StreamLimitStats {
+	interceptor.mu.Lock()
+	defer interceptor.mu.Unlock()
+
+	stats := StreamLimitStats{
+		Active:     interceptor.active,
+		Rejected:   interceptor.rejected,
+		Principals: make(map[string]int, len(interceptor.principals)),
+		IPs:        make(map[string]int, len(interceptor.ips)),
+	}
+	for k, v := range interceptor.principals {
+		stats.Principals[k] = v
+	}
+	for k, v := range interceptor.ips {
+		stats.IPs[k] = v
+	}
+
+	return stats
+}
+
+func (interceptor *StreamLimitServerInterceptor) acquire(principal, ip string) (string, error) {
+	interceptor.mu.Lock()
+	defer interceptor.mu.Unlock()
+
+	if limit := interceptor.config.MaxPerPrincipal; limit > 0 && principal != "" && interceptor.principals[principal] >= limit {
+		interceptor.rejected++
+		return "principal", status.Error(codes.ResourceExhausted, fmt.Sprintf("too many concurrent streams for principal, limit is %d", limit))
+	}
+	if limit := interceptor.config.MaxPerIP; limit > 0 && ip != "" && interceptor.ips[ip] >= limit {
+		interceptor.rejected++
+		return "ip", status.Error(codes.ResourceExhausted, fmt.Sprintf("too many concurrent streams from ip, limit is %d", limit))
+	}
+
+	interceptor.active++
+	if principal != "" {
+		interceptor.principals[principal]++
+	}
+	if ip != "" {
+		interceptor.ips[ip]++
+	}
+
+	return "", nil
+}
+
+func (interceptor *StreamLimitServerInterceptor) release(principal, ip string) {
+	interceptor.mu.Lock()
+	defer interceptor.mu.Unlock()
+
+	interceptor.active--
+	decrement(interceptor.principals, principal)
+	decrement(interceptor.ips, ip)
+}
+
+func decrement(counters map[string]int, key string) {
+	if key == "" {
+		return
+	}
+
+	if counters[key] <= 1 {
+		delete(counters, key)
+		return
+	}
+	counters[key]--
+}
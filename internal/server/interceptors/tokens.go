@@ -0,0 +1,60 @@
+package interceptors
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Identity is what a bearer token resolves to.
+type Identity struct {
+	Principal string
+	Roles     []string
+}
+
+func (i Identity) HasAnyRole(roles []string) bool {
+	for _, want := range roles {
+		for _, have := range i.Roles {
+			if want == have {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// LoadTokens reads a tokens file, one token per line:
+//
+//	<token> <principal> <role>[,<role>...]
+//
+// Empty lines and lines starting with # are ignored.
+func LoadTokens(path string) (map[string]Identity, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	tokens := make(map[string]Identity)
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("%s:%d: expected <token> <principal> <roles>", path, line)
+		}
+
+		tokens[fields[0]] = Identity{
+			Principal: fields[1],
+			Roles:     strings.Split(fields[2], ","),
+		}
+	}
+
+	return tokens, scanner.Err()
+}
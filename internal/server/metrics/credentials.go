@@ -0,0 +1,37 @@
+package metrics
+
+import (
+	"net"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// InstrumentCredentials counts failed server handshakes of the wrapped
+// transport credentials.
+func (m *Metrics) InstrumentCredentials(creds credentials.TransportCredentials) credentials.TransportCredentials {
+	return &instrumentedCredentials{TransportCredentials: creds, metrics: m}
+}
+
+type instrumentedCredentials struct {
+	credentials.TransportCredentials
+	metrics *Metrics
+}
+
+func (c *instrumentedCredentials) ServerHandshake(rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	conn, info, err := c.TransportCredentials.ServerHandshake(rawConn)
+	if err != nil {
+		c.metrics.tlsFailures.Inc()
+	}
+
+	return conn, info, err
+}
+
+func (c *instrumentedCredentials) Clone() credentials.TransportCredentials {
+	return &instrumentedCredentials{TransportCredentials: c.TransportCredentials.Clone(), metrics: c.metrics}
+}
+
+// TLSHandshakeFailed counts a handshake failed outside of the gRPC
+// credentials, e.g. on the single port listener.
+func (m *Metrics) TLSHandshakeFailed(error) {
+	m.tlsFailures.Inc()
+}
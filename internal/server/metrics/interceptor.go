@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"strings"
+
+	"google.golang.org/grpc"
+
+	"grpc-streaming/internal/server/hub"
+	pb "grpc-streaming/streaming/grpc"
+)
+
+// StreamInterceptor counts chat messages per room and message type, see
+// WithRooms.
+func (m *Metrics) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		stream grpc.ServerStream,
+		_ *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		return handler(srv, &countingStream{
+			ServerStream: stream,
+			metrics:      m,
+			room:         m.roomLabel(hub.RoomFromContext(stream.Context())),
+		})
+	}
+}
+
+type countingStream struct {
+	grpc.ServerStream
+	metrics *Metrics
+	room    string
+}
+
+func (s *countingStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	s.count("received", m)
+	return nil
+}
+
+func (s *countingStream) SendMsg(m interface{}) error {
+	if err := s.ServerStream.SendMsg(m); err != nil {
+		return err
+	}
+
+	s.count("sent", m)
+	return nil
+}
+
+func (s *countingStream) count(direction string, m interface{}) {
+	msgType := "other"
+	if msg, ok := m.(*pb.Message); ok {
+		msgType = strings.ToLower(msg.Type.String())
+	}
+
+	s.metrics.messages.WithLabelValues(direction, s.room, msgType).Inc()
+}
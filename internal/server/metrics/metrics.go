@@ -0,0 +1,190 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"grpc-streaming/internal/server/hub"
+	"grpc-streaming/internal/server/netlimit"
+)
+
+const namespace = "chat"
+
+// otherRoom labels the messages of the rooms not passed to WithRooms.
+const otherRoom = "other"
+
+// Metrics owns the server Prometheus registry. It implements
+// interceptors.Observer and is wired into gRPC with StatsHandler,
+// StreamInterceptor and InstrumentCredentials.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	activeStreams   *prometheus.GaugeVec
+	activeConns     prometheus.Gauge
+	messages        *prometheus.CounterVec
+	messageSize     *prometheus.HistogramVec
+	latency         *prometheus.HistogramVec
+	authFailures    *prometheus.CounterVec
+	tlsFailures     prometheus.Counter
+	rateLimited     *prometheus.CounterVec
+	rejectedStreams *prometheus.CounterVec
+
+	// rooms bound the room label, clients choose room names freely
+	rooms map[string]bool
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		activeStreams: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "active_streams",
+			Help:      "Number of streaming RPCs currently in flight.",
+		}, []string{"method"}),
+		activeConns: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "grpc_connections",
+			Help:      "Number of established gRPC transport connections.",
+		}),
+		messages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "stream_messages_total",
+			Help:      "Stream messages by direction, room and message type.",
+		}, []string{"direction", "room", "type"}),
+		messageSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "message_size_bytes",
+			Help:      "Size of received and sent messages.",
+			Buckets:   prometheus.ExponentialBuckets(16, 4, 8),
+		}, []string{"method", "direction"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "rpc_duration_seconds",
+			Help:      "RPC latency by method and status code, for streams this is the stream lifetime.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 4, 10),
+		}, []string{"method", "code"}),
+		authFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "auth_failures_total",
+			Help:      "Rejected authentications and authorizations by reason.",
+		}, []string{"method", "reason"}),
+		tlsFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "tls_handshake_failures_total",
+			Help:      "Failed server side TLS handshakes.",
+		}),
+		rateLimited: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rate_limited_total",
+			Help:      "Messages rejected by the rate limiter by scope.",
+		}, []string{"method", "scope"}),
+		rejectedStreams: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rejected_streams_total",
+			Help:      "Streams rejected by the concurrent stream limits.",
+		}, []string{"method", "reason"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.activeStreams,
+		m.activeConns,
+		m.messages,
+		m.messageSize,
+		m.latency,
+		m.authFailures,
+		m.tlsFailures,
+		m.rateLimited,
+		m.rejectedStreams,
+	)
+
+	return m.WithRooms(hub.DefaultRoom)
+}
+
+// WithRooms sets the rooms labelled in the message counters, the messages of
+// any other room are counted under "other".
+func (m *Metrics) WithRooms(rooms ...string) *Metrics {
+	m.rooms = make(map[string]bool, len(rooms))
+	for _, room := range rooms {
+		m.rooms[room] = true
+	}
+
+	return m
+}
+
+// roomLabel bounds the cardinality of the room label.
+func (m *Metrics) roomLabel(room string) string {
+	if m.rooms[room] {
+		return room
+	}
+
+	return otherRoom
+}
+
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+func (m *Metrics) AuthFailed(_ context.Context, method, reason string) {
+	m.authFailures.WithLabelValues(method, reason).Inc()
+}
+
+func (m *Metrics) RateLimited(_ context.Context, method, scope string) {
+	m.rateLimited.WithLabelValues(method, scope).Inc()
+}
+
+func (m *Metrics) StreamRejected(_ context.Context, method, reason string) {
+	m.rejectedStreams.WithLabelValues(method, reason).Inc()
+}
+
+// RegisterHub exports the hub queue depth and drop counters.
+func (m *Metrics) RegisterHub(h *hub.Hub) {
+	m.registry.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "hub_subscribers",
+			Help:      "Number of streams subscribed to the hub.",
+		}, func() float64 { return float64(h.Stats().Subscribers) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "hub_queue_depth",
+			Help:      "Total number of messages waiting in subscriber queues.",
+		}, func() float64 { return float64(h.Stats().QueueDepth) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "hub_max_queue_depth",
+			Help:      "Depth of the fullest subscriber queue.",
+		}, func() float64 { return float64(h.Stats().MaxQueueDepth) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "hub_dropped_messages_total",
+			Help:      "Messages dropped because a subscriber queue was full.",
+		}, func() float64 { return float64(h.Stats().Dropped) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "hub_disconnected_subscribers_total",
+			Help:      "Slow consumers disconnected by the hub.",
+		}, func() float64 { return float64(h.Stats().Disconnected) }),
+	)
+}
+
+// RegisterListener exports the connection limiting listener counters.
+func (m *Metrics) RegisterListener(lis *netlimit.Listener) {
+	m.registry.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "listener_active_connections",
+			Help:      "Number of open TCP connections on the listener.",
+		}, func() float64 { return float64(lis.Stats().Active) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "listener_rejected_connections_total",
+			Help:      "Connections closed because the max connections cap was reached.",
+		}, func() float64 { return float64(lis.Stats().Rejected) }),
+	)
+}
@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"context"
+
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+)
+
+type rpcKey struct{}
+
+type rpcInfo struct {
+	method string
+	stream bool
+}
+
+// StatsHandler observes every RPC handled by the server, including the ones
+// rejected by interceptors.
+func (m *Metrics) StatsHandler() stats.Handler {
+	return &statsHandler{metrics: m}
+}
+
+type statsHandler struct {
+	metrics *Metrics
+}
+
+func (h *statsHandler) TagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
+	return context.WithValue(ctx, rpcKey{}, &rpcInfo{method: info.FullMethodName})
+}
+
+func (h *statsHandler) HandleRPC(ctx context.Context, s stats.RPCStats) {
+	info, ok := ctx.Value(rpcKey{}).(*rpcInfo)
+	if !ok {
+		return
+	}
+
+	switch s := s.(type) {
+	case *stats.Begin:
+		if s.IsClientStream || s.IsServerStream {
+			info.stream = true
+			h.metrics.activeStreams.WithLabelValues(info.method).Inc()
+		}
+	case *stats.InPayload:
+		h.metrics.messageSize.WithLabelValues(info.method, "in").Observe(float64(s.Length))
+	case *stats.OutPayload:
+		h.metrics.messageSize.WithLabelValues(info.method, "out").Observe(float64(s.Length))
+	case *stats.End:
+		code := status.Code(s.Error).String()
+		h.metrics.latency.WithLabelValues(info.method, code).Observe(s.EndTime.Sub(s.BeginTime).Seconds())
+		if info.stream {
+			h.metrics.activeStreams.WithLabelValues(info.method).Dec()
+		}
+	}
+}
+
+func (h *statsHandler) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (h *statsHandler) HandleConn(_ context.Context, s stats.ConnStats) {
+	switch s.(type) {
+	case *stats.ConnBegin:
+		h.metrics.activeConns.Inc()
+	case *stats.ConnEnd:
+		h.metrics.activeConns.Dec()
+	}
+}
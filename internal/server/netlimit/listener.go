@@ -0,0 +1,72 @@
+package netlimit
+
+import (
+	"log/slog"
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+// Listener caps the number of simultaneously open connections. Connections
+// accepted above the cap are closed right away instead of queueing in the
+// backlog, so clients fail fast and the rejection is visible in Stats.
+type Listener struct {
+	net.Listener
+	maxConns int64
+
+	active   atomic.Int64
+	accepted atomic.Uint64
+	rejected atomic.Uint64
+}
+
+type Stats struct {
+	Active   int64
+	Accepted uint64
+	Rejected uint64
+}
+
+// NewListener wraps lis, maxConns <= 0 means unlimited.
+func NewListener(lis net.Listener, maxConns int) *Listener {
+	return &Listener{Listener: lis, maxConns: int64(maxConns)}
+}
+
+func (l *Listener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		if l.maxConns > 0 && l.active.Load() >= l.maxConns {
+			l.rejected.Add(1)
+			slog.With("remote", conn.RemoteAddr().String(), "maxConns", l.maxConns).
+				Warn("connection limit reached, closing connection")
+			_ = conn.Close()
+			continue
+		}
+
+		l.active.Add(1)
+		l.accepted.Add(1)
+
+		return &trackedConn{Conn: conn, release: func() { l.active.Add(-1) }}, nil
+	}
+}
+
+func (l *Listener) Stats() Stats {
+	return Stats{
+		Active:   l.active.Load(),
+		Accepted: l.accepted.Load(),
+		Rejected: l.rejected.Load(),
+	}
+}
+
+type trackedConn struct {
+	net.Conn
+	once    sync.Once
+	release func()
+}
+
+func (c *trackedConn) Close() error {
+	c.once.Do(c.release)
+	return c.Conn.Close()
+}
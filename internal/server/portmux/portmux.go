@@ -0,0 +1,277 @@
+// Package portmux serves gRPC and HTTP on one listener. Every accepted
+// connection is classified by its first bytes and handed to the listener of
+// its protocol:
+//
+//   - a TLS ClientHello is terminated here; clients offering only "h2" in
+//     ALPN, like gRPC clients, are gRPC, the others negotiate HTTP/1.1
+//   - a plaintext connection starting with the HTTP/2 preface is gRPC
+//   - anything else is HTTP/1.x
+//
+// Detection is per connection, not per request: the content-type of the
+// requests, application/grpc or not, is never inspected, every HTTP/2
+// connection goes to gRPC and the gRPC server answers its other requests 415.
+// HTTP clients therefore reach the HTTP listener over HTTP/1.1 only, which is
+// enough for the gateway, Server-Sent Events, WebSockets and gRPC-Web.
+package portmux
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"log/slog"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+)
+
+const defaultDetectTimeout = 10 * time.Second
+
+// http2Preface starts every HTTP/2 connection.
+var http2Preface = []byte("PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n")
+
+// tlsHandshakeRecord is the first byte of a TLS ClientHello.
+const tlsHandshakeRecord = 0x16
+
+type Config struct {
+	// TLS, if set, terminates TLS connections. Its NextProtos are ignored.
+	TLS *tls.Config
+	// AllowPlaintext accepts plaintext connections next to TLS ones, without
+	// TLS every connection is plaintext.
+	AllowPlaintext bool
+	// DetectTimeout bounds the TLS handshake and the detection, 10s by default.
+	DetectTimeout time.Duration
+	// OnHandshakeError is called for every failed TLS handshake.
+	OnHandshakeError func(error)
+}
+
+// Mux accepts the connections of a listener for GRPC and HTTP.
+type Mux struct {
+	root   net.Listener
+	config Config
+	grpc   *listener
+	http   *listener
+}
+
+func New(root net.Listener, config Config) *Mux {
+	if config.DetectTimeout <= 0 {
+		config.DetectTimeout = defaultDetectTimeout
+	}
+
+	return &Mux{
+		root:   root,
+		config: config,
+		grpc:   newListener(root.Addr()),
+		http:   newListener(root.Addr()),
+	}
+}
+
+// GRPC is the listener of the gRPC connections, serve it with grpc.Server
+// using Credentials when TLS is on.
+func (m *Mux) GRPC() net.Listener {
+	return m.grpc
+}
+
+// HTTP is the listener of the HTTP/1.x connections, TLS ones are *tls.Conn so
+// http.Server fills Request.TLS.
+func (m *Mux) HTTP() net.Listener {
+	return m.http
+}
+
+// Serve accepts connections until the root listener is closed.
+func (m *Mux) Serve() error {
+	defer m.grpc.Close()
+	defer m.http.Close()
+
+	for {
+		conn, err := m.root.Accept()
+		if err != nil {
+			return err
+		}
+
+		go m.dispatch(conn)
+	}
+}
+
+// Close closes the root listener, Serve returns and the protocol listeners
+// stop accepting.
+func (m *Mux) Close() error {
+	return m.root.Close()
+}
+
+func (m *Mux) dispatch(conn net.Conn) {
+	logger := slog.With("remote", conn.RemoteAddr().String())
+
+	target, conn, err := m.detect(conn)
+	if err != nil {
+		logger.With("error", err).Warn("cannot detect the connection protocol, closing it")
+		_ = conn.Close()
+		return
+	}
+
+	if !target.deliver(conn) {
+		_ = conn.Close()
+	}
+}
+
+// detect classifies conn and returns the connection to serve, TLS terminated.
+func (m *Mux) detect(conn net.Conn) (*listener, net.Conn, error) {
+	_ = conn.SetDeadline(time.Now().Add(m.config.DetectTimeout))
+	defer func() {
+		_ = conn.SetDeadline(time.Time{})
+	}()
+
+	peeked := &peekedConn{Conn: conn, reader: bufio.NewReader(conn)}
+	first, err := peeked.reader.Peek(1)
+	if err != nil {
+		return nil, conn, err
+	}
+
+	if first[0] == tlsHandshakeRecord {
+		if m.config.TLS == nil {
+			return nil, conn, errors.New("TLS is not enabled")
+		}
+		return m.handshake(peeked)
+	}
+	if m.config.TLS != nil && !m.config.AllowPlaintext {
+		return nil, conn, errors.New("plaintext connections are not allowed")
+	}
+
+	// Префикс сравнивается по мере поступления байт: короткий HTTP/1-запрос
+	// не должен ждать полных 24 байт
+	for n := 1; n <= len(http2Preface); n++ {
+		prefix, err := peeked.reader.Peek(n)
+		if err != nil {
+			return nil, conn, err
+		}
+		if !bytes.HasPrefix(http2Preface, prefix) {
+			return m.http, peeked, nil
+		}
+	}
+
+	return m.grpc, peeked, nil
+}
+
+func (m *Mux) handshake(conn *peekedConn) (*listener, net.Conn, error) {
+	config := m.config.TLS.Clone()
+	config.NextProtos = nil
+	config.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		// gRPC-клиенты предлагают только h2, HTTP-клиенты еще и http/1.1
+		alpn := config.Clone()
+		alpn.GetConfigForClient = nil
+		if slices.Contains(hello.SupportedProtos, "http/1.1") || !slices.Contains(hello.SupportedProtos, "h2") {
+			alpn.NextProtos = []string{"http/1.1"}
+		} else {
+			alpn.NextProtos = []string{"h2"}
+		}
+		return alpn, nil
+	}
+
+	tlsConn := tls.Server(conn, config)
+	if err := tlsConn.HandshakeContext(context.Background()); err != nil {
+		if m.config.OnHandshakeError != nil {
+			m.config.OnHandshakeError(err)
+		}
+		return nil, conn, err
+	}
+
+	if tlsConn.ConnectionState().NegotiatedProtocol == "h2" {
+		return m.grpc, tlsConn, nil
+	}
+
+	return m.http, tlsConn, nil
+}
+
+// Credentials let grpc.Server use the TLS terminated by the mux: the gRPC
+// connections keep their TLS state as peer AuthInfo, for mutual TLS
+// principals. Plaintext connections have no AuthInfo.
+func Credentials() credentials.TransportCredentials {
+	return terminatedTLS{}
+}
+
+type terminatedTLS struct{}
+
+func (terminatedTLS) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return conn, nil, nil
+	}
+
+	return conn, credentials.TLSInfo{
+		State:          tlsConn.ConnectionState(),
+		CommonAuthInfo: credentials.CommonAuthInfo{SecurityLevel: credentials.PrivacyAndIntegrity},
+	}, nil
+}
+
+func (terminatedTLS) ClientHandshake(context.Context, string, net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return nil, nil, errors.New("portmux credentials are server side only")
+}
+
+func (terminatedTLS) Info() credentials.ProtocolInfo {
+	return credentials.ProtocolInfo{SecurityProtocol: "tls"}
+}
+
+func (c terminatedTLS) Clone() credentials.TransportCredentials {
+	return c
+}
+
+func (terminatedTLS) OverrideServerName(string) error {
+	return nil
+}
+
+// peekedConn replays the bytes read during the detection.
+type peekedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *peekedConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+// listener hands the detected connections to one server.
+type listener struct {
+	addr  net.Addr
+	conns chan net.Conn
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+func newListener(addr net.Addr) *listener {
+	return &listener{addr: addr, conns: make(chan net.Conn), done: make(chan struct{})}
+}
+
+func (l *listener) deliver(conn net.Conn) bool {
+	select {
+	case l.conns <- conn:
+		return true
+	case <-l.done:
+		return false
+	}
+}
+
+func (l *listener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *listener) Close() error {
+	l.closeOnce.Do(func() {
+		close(l.done)
+	})
+
+	return nil
+}
+
+func (l *listener) Addr() net.Addr {
+	return l.addr
+}
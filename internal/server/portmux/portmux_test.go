@@ -0,0 +1,185 @@
+package portmux
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// serve runs a mux with a gRPC health service and an HTTP handler answering
+// "http" on a local port and returns its address.
+func serve(t *testing.T, config Config) string {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := New(lis, config)
+
+	var opts []grpc.ServerOption
+	if config.TLS != nil {
+		opts = append(opts, grpc.Creds(Credentials()))
+	}
+	grpcServer := grpc.NewServer(opts...)
+	healthpb.RegisterHealthServer(grpcServer, health.NewServer())
+	httpServer := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "http")
+	})}
+
+	go func() {
+		_ = grpcServer.Serve(mux.GRPC())
+	}()
+	go func() {
+		_ = httpServer.Serve(mux.HTTP())
+	}()
+	go func() {
+		_ = mux.Serve()
+	}()
+	t.Cleanup(func() {
+		_ = mux.Close()
+		grpcServer.Stop()
+		_ = httpServer.Close()
+	})
+
+	return lis.Addr().String()
+}
+
+// selfSigned returns a server certificate for 127.0.0.1 and the pool that
+// trusts it.
+func selfSigned(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+func checkHealth(t *testing.T, addr string, creds credentials.TransportCredentials) {
+	t.Helper()
+
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil || resp.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("gRPC health check: %v, %v", resp, err)
+	}
+}
+
+// checkRefused asserts the failure of an HTTP/2 HTTP client: the gRPC server
+// gets the connection and names the content-type it refused.
+func checkRefused(t *testing.T, client *http.Client, url string) {
+	t.Helper()
+
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.ProtoMajor != 2 || resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("got %s %d, want HTTP/2 415", resp.Proto, resp.StatusCode)
+	}
+	if message := resp.Header.Get("Grpc-Message"); !strings.Contains(message, "content-type") {
+		t.Errorf("grpc-message %q does not name the content-type", message)
+	}
+}
+
+func TestPlaintext(t *testing.T) {
+	addr := serve(t, Config{})
+
+	resp, err := http.Get("http://" + addr + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "http" {
+		t.Errorf("HTTP/1.1 got %q, want the HTTP handler", body)
+	}
+
+	checkHealth(t, addr, insecure.NewCredentials())
+
+	// h2c с предварительным знанием неотличим от gRPC
+	h2c := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}}
+	checkRefused(t, h2c, "http://"+addr+"/")
+}
+
+func TestTLS(t *testing.T) {
+	cert, pool := selfSigned(t)
+	addr := serve(t, Config{TLS: &tls.Config{Certificates: []tls.Certificate{cert}}})
+
+	// Клиент, предлагающий h2 и http/1.1, получает HTTP/1.1
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: pool},
+		ForceAttemptHTTP2: true,
+	}}
+	resp, err := client.Get("https://" + addr + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.ProtoMajor != 1 || string(body) != "http" {
+		t.Errorf("got %s %q, want the HTTP handler over HTTP/1.1", resp.Proto, body)
+	}
+
+	checkHealth(t, addr, credentials.NewTLS(&tls.Config{RootCAs: pool}))
+
+	// Клиент, предлагающий только h2, неотличим от gRPC
+	h2Only := &http.Client{Transport: &http2.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	checkRefused(t, h2Only, "https://"+addr+"/")
+}
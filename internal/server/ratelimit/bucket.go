@@ -0,0 +1,52 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+// bucket is a classic token bucket, a zero rate means unlimited.
+type bucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(rate, burst float64, now time.Time) *bucket {
+	return &bucket{rate: rate, burst: burst, tokens: burst, last: now}
+}
+
+func (b *bucket) refill(now time.Time) {
+	if b.rate <= 0 {
+		return
+	}
+
+	elapsed := now.Sub(b.last).Seconds()
+	b.last = now
+	if elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed*b.rate)
+	}
+}
+
+// wait returns how long the caller has to wait before n tokens are available.
+func (b *bucket) wait(n float64) time.Duration {
+	if b.rate <= 0 || b.tokens >= n {
+		return 0
+	}
+
+	return time.Duration((n - b.tokens) / b.rate * float64(time.Second))
+}
+
+func (b *bucket) take(n float64) {
+	if b.rate > 0 {
+		b.tokens -= n
+	}
+}
+
+// give returns n tokens taken before, up to the burst.
+func (b *bucket) give(n float64) {
+	if b.rate > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+n)
+	}
+}
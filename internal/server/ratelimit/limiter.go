@@ -0,0 +1,125 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limit configures a single scope of the limiter. Zero values disable the
+// corresponding dimension.
+type Limit struct {
+	// Messages allowed per second.
+	Messages float64
+	// Bytes allowed per second, messages bigger than the byte burst are
+	// always rejected.
+	Bytes float64
+	// Burst is how many seconds worth of traffic may be spent at once.
+	Burst time.Duration
+}
+
+func (l Limit) Enabled() bool {
+	return l.Messages > 0 || l.Bytes > 0
+}
+
+func (l Limit) burst(rate float64) float64 {
+	burst := rate * l.Burst.Seconds()
+	if burst < 1 {
+		burst = 1
+	}
+
+	return burst
+}
+
+// Limiter keeps a pair of message/byte buckets per key.
+type Limiter struct {
+	limit   Limit
+	idleTTL time.Duration
+	now     func() time.Time
+
+	mu        sync.Mutex
+	entries   map[string]*entry
+	lastSweep time.Time
+}
+
+type entry struct {
+	messages *bucket
+	bytes    *bucket
+	seen     time.Time
+}
+
+func NewLimiter(limit Limit) *Limiter {
+	return &Limiter{
+		limit:   limit,
+		idleTTL: 10 * time.Minute,
+		now:     time.Now,
+		entries: make(map[string]*entry),
+	}
+}
+
+// Allow consumes one message of the given size for key. When either bucket
+// is exhausted nothing is consumed and the required wait time is returned.
+func (l *Limiter) Allow(key string, size int) (bool, time.Duration) {
+	if !l.limit.Enabled() {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	e, ok := l.entries[key]
+	if !ok {
+		e = &entry{
+			messages: newBucket(l.limit.Messages, l.limit.burst(l.limit.Messages), now),
+			bytes:    newBucket(l.limit.Bytes, l.limit.burst(l.limit.Bytes), now),
+		}
+		l.entries[key] = e
+	}
+	e.seen = now
+
+	e.messages.refill(now)
+	e.bytes.refill(now)
+
+	wait := max(e.messages.wait(1), e.bytes.wait(float64(size)))
+	if wait > 0 {
+		return false, wait
+	}
+
+	e.messages.take(1)
+	e.bytes.take(float64(size))
+
+	return true, 0
+}
+
+// Refund gives back a message of the given size consumed by Allow, e.g. when
+// another limiter rejected it afterwards.
+func (l *Limiter) Refund(key string, size int) {
+	if !l.limit.Enabled() {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if e, ok := l.entries[key]; ok {
+		e.messages.give(1)
+		e.bytes.give(float64(size))
+	}
+}
+
+// sweep drops buckets of keys that have been idle for a while, must be called
+// with l.mu held.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.idleTTL {
+		return
+	}
+	l.lastSweep = now
+
+	for key, e := range l.entries {
+		if now.Sub(e.seen) > l.idleTTL {
+			delete(l.entries, key)
+		}
+	}
+}
@@ -3,12 +3,25 @@ package tls
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/pem"
 	"fmt"
 	"google.golang.org/grpc/credentials"
 	"os"
+	"time"
 )
 
 func LoadServerTLSCredentials(isMutualTLS bool) (credentials.TransportCredentials, error) {
+	config, err := LoadServerTLSConfig(isMutualTLS)
+	if err != nil {
+		return nil, err
+	}
+
+	return credentials.NewTLS(config), nil
+}
+
+// LoadServerTLSConfig is the config behind LoadServerTLSCredentials, for the
+// HTTP listeners that share the gRPC server's certificates.
+func LoadServerTLSConfig(isMutualTLS bool) (*tls.Config, error) {
 	if isMutualTLS {
 		return mutualTLS()
 	}
@@ -16,24 +29,24 @@ func LoadServerTLSCredentials(isMutualTLS bool) (credentials.TransportCredential
 	return noClientCert()
 }
 
-func noClientCert() (credentials.TransportCredentials, error) {
+func noClientCert() (*tls.Config, error) {
 	// Load server's certificate and private key
 	serverCert, err := tls.LoadX509KeyPair("cert/server-cert.pem", "cert/server-key.pem")
 	if err != nil {
 		return nil, err
 	}
 
-	// Create the credentials and return it
+	// Create the config and return it
 	config := &tls.Config{
 		MinVersion:   tls.VersionTLS13,
 		Certificates: []tls.Certificate{serverCert},
 		ClientAuth:   tls.NoClientCert,
 	}
 
-	return credentials.NewTLS(config), nil
+	return config, nil
 }
 
-func mutualTLS() (credentials.TransportCredentials, error) {
+func mutualTLS() (*tls.Config, error) {
 	// Load certificate of the CA who signed client's certificate
 	pemClientCA, err := os.ReadFile("cert/ca-cert.pem")
 	if err != nil {
@@ -51,7 +64,7 @@ func mutualTLS() (credentials.TransportCredentials, error) {
 		return nil, err
 	}
 
-	// Create the credentials and return it
+	// Create the config and return it
 	config := &tls.Config{
 		MinVersion:   tls.VersionTLS13,
 		Certificates: []tls.Certificate{serverCert},
@@ -59,5 +72,70 @@ func mutualTLS() (credentials.TransportCredentials, error) {
 		ClientCAs:    certPool,
 	}
 
-	return credentials.NewTLS(config), nil
+	return config, nil
+}
+
+// CheckServerCertificate fails when the server certificate can't be loaded,
+// is expired or expires within the given window.
+func CheckServerCertificate(window time.Duration) error {
+	serverCert, err := tls.LoadX509KeyPair("cert/server-cert.pem", "cert/server-key.pem")
+	if err != nil {
+		return err
+	}
+
+	leaf, err := x509.ParseCertificate(serverCert.Certificate[0])
+	if err != nil {
+		return err
+	}
+
+	if time.Now().Add(window).After(leaf.NotAfter) {
+		return fmt.Errorf("server certificate expires at %s", leaf.NotAfter.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+// Certificate is a parsed certificate the server is configured with, Name is
+// "server" or "ca".
+type Certificate struct {
+	Name string
+	*x509.Certificate
+}
+
+// LoadCertificates parses the server certificate and, with mutual TLS, the CA
+// certificates client certificates are verified against.
+func LoadCertificates(isMutualTLS bool) ([]Certificate, error) {
+	serverCert, err := tls.LoadX509KeyPair("cert/server-cert.pem", "cert/server-key.pem")
+	if err != nil {
+		return nil, err
+	}
+
+	leaf, err := x509.ParseCertificate(serverCert.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+
+	certs := []Certificate{{Name: "server", Certificate: leaf}}
+	if !isMutualTLS {
+		return certs, nil
+	}
+
+	pemClientCA, err := os.ReadFile("cert/ca-cert.pem")
+	if err != nil {
+		return nil, err
+	}
+
+	for block, rest := pem.Decode(pemClientCA); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		ca, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, Certificate{Name: "ca", Certificate: ca})
+	}
+
+	return certs, nil
 }
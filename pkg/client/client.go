@@ -0,0 +1,436 @@
+// Package client is a Go SDK for the chat server: it keeps one room stream
+// open, rejoins it after transport failures and exposes the unary Chat RPCs.
+package client
+
+import (
+	"context"
+	"errors"
+	"grpc-streaming/internal/client/interceptors"
+	"io"
+	"sync"
+	"time"
+
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/status"
+	pb "grpc-streaming/streaming/grpc"
+)
+
+// eventBuffer is the capacity of the Events channel, events are dropped
+// rather than stalling the stream when nobody reads them.
+const eventBuffer = 16
+
+var (
+	// ErrNotInRoom is returned by Send and History before Join.
+	ErrNotInRoom = errors.New("not in a room")
+	// ErrClosed is returned by every call after Close.
+	ErrClosed = errors.New("client is closed")
+)
+
+type State int
+
+const (
+	StateJoined State = iota
+	StateLeft
+	StateReconnecting
+	StateDisconnected
+)
+
+func (s State) String() string {
+	switch s {
+	case StateJoined:
+		return "joined"
+	case StateLeft:
+		return "left"
+	case StateReconnecting:
+		return "reconnecting"
+	default:
+		return "disconnected"
+	}
+}
+
+// Event reports a change of the room stream.
+type Event struct {
+	State State
+	Room  string
+	// Err is why the stream ended, for StateReconnecting and StateDisconnected.
+	Err error
+	// Retry is the delay before the next rejoin attempt.
+	Retry time.Duration
+}
+
+// Client keeps the caller in at most one room and rejoins it with exponential
+// backoff when the stream fails with a transient error. Incoming chat and
+// system messages are delivered on Messages, heartbeats are handled
+// internally. A Client is safe for concurrent use.
+type Client struct {
+	conn *grpc.ClientConn
+	chat pb.ChatClient
+	opts *options
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	messages chan *pb.Message
+	events   chan Event
+
+	mu   sync.Mutex
+	room *roomStream
+	// name is the room the caller is in, it survives the stream while rejoining.
+	name string
+
+	// lifeMu guards closed and wg, every goroutine of the client is tracked
+	// so the channels are closed only once nothing can send on them.
+	lifeMu    sync.Mutex
+	closed    bool
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// New creates a client for the server at address. Like grpc.NewClient it
+// performs no I/O, the connection is established by Connect or the first call.
+func New(address string, opts ...Option) (*Client, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+
+	auth := interceptors.NewAuthClientInterceptor(o.token)
+	requestID := interceptors.NewRequestIDClientInterceptor()
+	dialOptions := []grpc.DialOption{
+		grpc.WithTransportCredentials(o.creds),
+		grpc.WithChainUnaryInterceptor(requestID.Unary(), auth.Unary()),
+		grpc.WithChainStreamInterceptor(requestID.Stream(), auth.Stream()),
+	}
+	if o.keepalive != nil {
+		dialOptions = append(dialOptions, grpc.WithKeepaliveParams(*o.keepalive))
+	}
+
+	conn, err := grpc.NewClient(address, append(dialOptions, o.dialOptions...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Client{
+		conn:     conn,
+		chat:     pb.NewChatClient(conn),
+		opts:     o,
+		ctx:      ctx,
+		cancel:   cancel,
+		messages: make(chan *pb.Message, o.messageBuffer),
+		events:   make(chan Event, eventBuffer),
+	}, nil
+}
+
+// Connect waits until the connection is ready or ctx is done.
+func (c *Client) Connect(ctx context.Context) error {
+	c.conn.Connect()
+
+	for {
+		state := c.conn.GetState()
+		switch state {
+		case connectivity.Ready:
+			return nil
+		case connectivity.Shutdown:
+			return ErrClosed
+		}
+
+		if !c.conn.WaitForStateChange(ctx, state) {
+			return ctx.Err()
+		}
+	}
+}
+
+// Conn exposes the underlying connection, e.g. for health checks.
+func (c *Client) Conn() *grpc.ClientConn {
+	return c.conn
+}
+
+// Messages delivers the messages of the current room. The channel is closed
+// by Close, a reader falling behind stalls the stream.
+func (c *Client) Messages() <-chan *pb.Message {
+	return c.messages
+}
+
+// Events reports joins, leaves and reconnects. The channel is closed by Close.
+func (c *Client) Events() <-chan Event {
+	return c.events
+}
+
+// Room returns the current room, empty when the caller is in none.
+func (c *Client) Room() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.name
+}
+
+// Join leaves the current room and joins another one.
+func (c *Client) Join(room string) error {
+	c.Leave()
+
+	joined, err := c.joinRoom(room)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.room, c.name = joined, room
+	c.mu.Unlock()
+
+	c.emit(Event{State: StateJoined, Room: room})
+	if !c.spawn(func() { c.watch(joined) }) {
+		c.Leave()
+		return ErrClosed
+	}
+
+	return nil
+}
+
+// Leave closes the room stream, waiting briefly for the server to finish it.
+func (c *Client) Leave() {
+	c.mu.Lock()
+	room, name := c.room, c.name
+	c.room, c.name = nil, ""
+	c.mu.Unlock()
+
+	if room == nil {
+		return
+	}
+
+	_ = room.Close()
+	c.emit(Event{State: StateLeft, Room: name})
+}
+
+// Send publishes body to the current room.
+func (c *Client) Send(body string) error {
+	c.mu.Lock()
+	room := c.room
+	c.mu.Unlock()
+
+	if room == nil {
+		return ErrNotInRoom
+	}
+
+	return room.Send(body)
+}
+
+// Who lists the principals connected to room.
+func (c *Client) Who(ctx context.Context, room string) ([]string, error) {
+	resp, err := c.chat.Who(ctx, &pb.WhoRequest{Room: room})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Principals, nil
+}
+
+// History returns up to limit recent messages of the current room, 0 means
+// the server default.
+func (c *Client) History(ctx context.Context, limit int) ([]*pb.Message, error) {
+	room := c.Room()
+	if room == "" {
+		return nil, ErrNotInRoom
+	}
+
+	resp, err := c.chat.History(ctx, &pb.HistoryRequest{Room: room, Limit: int32(limit)})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Messages, nil
+}
+
+// Feed streams the messages of the server's feed and calls fn for each of
+// them as it arrives. params are passed to the service behind the feed,
+// messages without a room get room and limit 0 means no limit. An error of
+// fn cancels the call and is returned.
+func (c *Client) Feed(ctx context.Context, room string, params map[string]string, limit int, fn func(*pb.Message) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := c.chat.Feed(ctx, &pb.FeedRequest{Room: room, Params: params, Limit: int32(limit)})
+	if err != nil {
+		return err
+	}
+
+	for {
+		msg, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err = fn(msg); err != nil {
+			return err
+		}
+	}
+}
+
+// Rooms lists the active rooms with their participant counts.
+func (c *Client) Rooms(ctx context.Context) ([]*pb.RoomSummary, error) {
+	resp, err := c.chat.Rooms(ctx, &pb.RoomsRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Rooms, nil
+}
+
+// Close leaves the room, stops rejoining, closes the Messages and Events
+// channels and the connection. It is safe to call more than once.
+func (c *Client) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		c.lifeMu.Lock()
+		c.closed = true
+		c.lifeMu.Unlock()
+
+		c.cancel()
+		c.Leave()
+		c.wg.Wait()
+
+		close(c.messages)
+		close(c.events)
+		err = c.conn.Close()
+	})
+
+	return err
+}
+
+// spawn runs fn in a tracked goroutine, it refuses once the client is closed.
+func (c *Client) spawn(fn func()) bool {
+	c.lifeMu.Lock()
+	defer c.lifeMu.Unlock()
+
+	if c.closed {
+		return false
+	}
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		fn()
+	}()
+
+	return true
+}
+
+func (c *Client) deliver(msg *pb.Message) {
+	select {
+	case c.messages <- msg:
+	case <-c.ctx.Done():
+	}
+}
+
+func (c *Client) emit(ev Event) {
+	c.lifeMu.Lock()
+	defer c.lifeMu.Unlock()
+
+	if c.closed && ev.State != StateLeft {
+		return
+	}
+
+	select {
+	case c.events <- ev:
+	default:
+		c.opts.logger.With("state", ev.State, "room", ev.Room).Warn("event dropped, nobody reads the events")
+	}
+}
+
+// watch waits for the stream to end and rejoins its room unless the caller
+// left it meanwhile or the failure is not worth retrying.
+func (c *Client) watch(stream *roomStream) {
+	<-stream.Done()
+	err := stream.Err()
+
+	c.mu.Lock()
+	if c.room != stream {
+		c.mu.Unlock()
+		return
+	}
+	if !retryable(err) {
+		c.room, c.name = nil, ""
+		c.mu.Unlock()
+		c.emit(Event{State: StateDisconnected, Room: stream.Room(), Err: err})
+		return
+	}
+	c.mu.Unlock()
+
+	for backoff := c.opts.minBackoff; ; backoff = min(2*backoff, c.opts.maxBackoff) {
+		c.emit(Event{State: StateReconnecting, Room: stream.Room(), Err: err, Retry: backoff})
+
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		if !c.current(stream) {
+			return
+		}
+
+		// Без блокировки: пока идет подключение, Send, Leave и Close не ждут
+		var joined *roomStream
+		joined, err = c.joinRoom(stream.Room())
+		if err != nil {
+			continue
+		}
+
+		c.mu.Lock()
+		if c.room != stream {
+			// Комнату покинули или сменили, пока шло подключение
+			c.mu.Unlock()
+			_ = joined.Close()
+			return
+		}
+		c.room = joined
+		c.mu.Unlock()
+
+		c.emit(Event{State: StateJoined, Room: joined.Room()})
+		c.spawn(func() { c.watch(joined) })
+		return
+	}
+}
+
+// current tells whether stream is still the stream of the current room.
+func (c *Client) current(stream *roomStream) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.room == stream
+}
+
+// MessageStatus returns why the server refused to publish a message, with
+// the details set by the server's message processors, for ERROR messages.
+// It is nil for every other message type.
+func MessageStatus(msg *pb.Message) *status.Status {
+	if msg.Type != pb.Message_ERROR || msg.Status == nil {
+		return nil
+	}
+
+	return status.FromProto(&spb.Status{Code: msg.Status.Code, Message: msg.Status.Message, Details: msg.Status.Details})
+}
+
+// retryable tells transient transport and overload failures apart from the
+// server deliberately ending the stream, e.g. an administrator kicking it.
+func retryable(err error) bool {
+	if errors.Is(err, ErrServerIdle) {
+		return true
+	}
+
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.DeadlineExceeded:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,119 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"grpc-streaming/pkg/server"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestNewRejectsInvalidOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  Option
+	}{
+		{"zero heartbeat interval", WithHeartbeat(0, time.Minute)},
+		{"negative idle timeout", WithHeartbeat(time.Second, -time.Minute)},
+		{"zero backoff", WithReconnectBackoff(0, time.Second)},
+		{"max backoff below min", WithReconnectBackoff(time.Second, time.Millisecond)},
+		{"negative message buffer", WithMessageBuffer(-1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New("localhost:50051", tt.opt)
+			if err == nil {
+				_ = c.Close()
+				t.Fatal("New accepted the options")
+			}
+		})
+	}
+
+	c, err := New("localhost:50051", WithHeartbeat(time.Second, 3*time.Second))
+	if err != nil {
+		t.Fatalf("valid options: %v", err)
+	}
+	_ = c.Close()
+}
+
+func TestLeaveWhileRejoining(t *testing.T) {
+	lis := bufconn.Listen(1 << 20)
+	srv, err := server.New(server.WithListener(lis))
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		_ = srv.Serve()
+	}()
+
+	// Первое подключение проходит, повторные висят, пока тест не закончится
+	gate := make(chan struct{})
+	var dials atomic.Int32
+	c, err := New("passthrough:///bufconn",
+		WithReconnectBackoff(10*time.Millisecond, 10*time.Millisecond),
+		WithDialOptions(grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			if dials.Add(1) > 1 {
+				<-gate
+				return nil, errors.New("gate closed")
+			}
+			return lis.DialContext(ctx)
+		})),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	defer close(gate)
+
+	if err := c.Join("general"); err != nil {
+		t.Fatal(err)
+	}
+	// Получив ответ, gRPC больше не повторяет стрим прозрачно и сообщает об обрыве
+	if err := c.Send("hi"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-c.Messages():
+	case <-time.After(5 * time.Second):
+		t.Fatal("no echo of the message")
+	}
+	srv.Stop()
+
+	deadline := time.After(5 * time.Second)
+	for reconnecting := false; !reconnecting; {
+		select {
+		case ev := <-c.Events():
+			reconnecting = ev.State == StateReconnecting
+		case <-deadline:
+			t.Fatal("the client did not try to rejoin")
+		}
+	}
+	// После паузы watch уже ждет подключения в joinRoom
+	for dials.Load() < 2 {
+		select {
+		case <-deadline:
+			t.Fatal("the client did not dial again")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	left := make(chan struct{})
+	go func() {
+		c.Leave()
+		close(left)
+	}()
+	select {
+	case <-left:
+	case <-time.After(time.Second):
+		t.Fatal("Leave waited for the rejoin attempt")
+	}
+	if room := c.Room(); room != "" {
+		t.Errorf("room = %q after Leave", room)
+	}
+}
@@ -0,0 +1,137 @@
+package client
+
+import (
+	"crypto/tls"
+	"errors"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+)
+
+const (
+	defaultHeartbeatInterval = 15 * time.Second
+	defaultIdleTimeout       = 45 * time.Second
+	defaultMinBackoff        = time.Second
+	defaultMaxBackoff        = 30 * time.Second
+	defaultMessageBuffer     = 64
+)
+
+// Option configures a Client.
+type Option func(*options)
+
+type options struct {
+	token             string
+	creds             credentials.TransportCredentials
+	keepalive         *keepalive.ClientParameters
+	heartbeatInterval time.Duration
+	idleTimeout       time.Duration
+	minBackoff        time.Duration
+	maxBackoff        time.Duration
+	messageBuffer     int
+	logger            *slog.Logger
+	dialOptions       []grpc.DialOption
+}
+
+func defaultOptions() *options {
+	return &options{
+		creds:             insecure.NewCredentials(),
+		heartbeatInterval: defaultHeartbeatInterval,
+		idleTimeout:       defaultIdleTimeout,
+		minBackoff:        defaultMinBackoff,
+		maxBackoff:        defaultMaxBackoff,
+		messageBuffer:     defaultMessageBuffer,
+		logger:            slog.Default(),
+	}
+}
+
+// validate checks the options New cannot work with: the heartbeat ticker,
+// the idle check and the rejoin backoff need positive durations.
+func (o *options) validate() error {
+	var errs []error
+	if o.heartbeatInterval <= 0 {
+		errs = append(errs, errors.New("heartbeat interval must be positive"))
+	}
+	if o.idleTimeout <= 0 {
+		errs = append(errs, errors.New("idle timeout must be positive"))
+	}
+	if o.minBackoff <= 0 || o.maxBackoff < o.minBackoff {
+		errs = append(errs, errors.New("reconnect backoff must be positive with max not below min"))
+	}
+	if o.messageBuffer < 0 {
+		errs = append(errs, errors.New("message buffer must not be negative"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// WithToken sends token as bearer authorization with every call.
+func WithToken(token string) Option {
+	return func(o *options) {
+		o.token = token
+	}
+}
+
+// WithTLS connects over TLS, cfg carries the root CAs and, for mutual TLS,
+// the client certificate.
+func WithTLS(cfg *tls.Config) Option {
+	return func(o *options) {
+		o.creds = credentials.NewTLS(cfg)
+	}
+}
+
+// WithTransportCredentials replaces the default insecure transport.
+func WithTransportCredentials(creds credentials.TransportCredentials) Option {
+	return func(o *options) {
+		o.creds = creds
+	}
+}
+
+// WithKeepalive sets the HTTP/2 keepalive pings of the connection.
+func WithKeepalive(params keepalive.ClientParameters) Option {
+	return func(o *options) {
+		o.keepalive = &params
+	}
+}
+
+// WithHeartbeat sets how often a heartbeat frame is sent on the room stream
+// and how long the server may stay silent before the stream is rejoined, New
+// rejects values that are not positive.
+func WithHeartbeat(interval, idleTimeout time.Duration) Option {
+	return func(o *options) {
+		o.heartbeatInterval = interval
+		o.idleTimeout = idleTimeout
+	}
+}
+
+// WithReconnectBackoff bounds the exponential backoff between rejoin attempts.
+func WithReconnectBackoff(minBackoff, maxBackoff time.Duration) Option {
+	return func(o *options) {
+		o.minBackoff = minBackoff
+		o.maxBackoff = maxBackoff
+	}
+}
+
+// WithMessageBuffer sets the capacity of the Messages channel.
+func WithMessageBuffer(size int) Option {
+	return func(o *options) {
+		o.messageBuffer = size
+	}
+}
+
+// WithLogger sets the logger of the stream goroutines, slog.Default() by default.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithDialOptions appends raw gRPC dial options, e.g. a stats handler.
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(o *options) {
+		o.dialOptions = append(o.dialOptions, opts...)
+	}
+}
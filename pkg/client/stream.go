@@ -0,0 +1,172 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	pb "grpc-streaming/streaming/grpc"
+)
+
+// closeTimeout bounds how long leaving a room waits for the server to finish
+// the stream after CloseSend.
+const closeTimeout = 2 * time.Second
+
+// ErrServerIdle ends a room stream on which the server stayed silent for
+// longer than the idle timeout, the room is rejoined.
+var ErrServerIdle = errors.New("server heartbeat timeout")
+
+// roomStream is a ChatStream joined to a single room. It sends heartbeats,
+// hides the server ones from the Messages channel and gives up when the
+// server stays silent for longer than the idle timeout.
+type roomStream struct {
+	room              string
+	stream            pb.Chat_ChatStreamClient
+	cancel            context.CancelFunc
+	heartbeatInterval time.Duration
+	idleTimeout       time.Duration
+	logger            *slog.Logger
+
+	// sendMu serializes Send calls of the heartbeat goroutine and the caller.
+	sendMu   sync.Mutex
+	lastSeen atomic.Int64
+
+	done      chan struct{}
+	closeOnce sync.Once
+	err       error
+}
+
+func (c *Client) joinRoom(room string) (*roomStream, error) {
+	// Стрим переживает отмену ctx, чтобы при выходе успеть отправить CloseSend
+	streamCtx, cancel := context.WithCancel(context.WithoutCancel(c.ctx))
+	stream, err := c.chat.ChatStream(metadata.AppendToOutgoingContext(streamCtx, "room", room))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	r := &roomStream{
+		room:              room,
+		stream:            stream,
+		cancel:            cancel,
+		heartbeatInterval: c.opts.heartbeatInterval,
+		idleTimeout:       c.opts.idleTimeout,
+		logger:            c.opts.logger.With("room", room),
+		done:              make(chan struct{}),
+	}
+	r.lastSeen.Store(time.Now().UnixNano())
+
+	if !c.spawn(func() { r.receive(c.deliver) }) {
+		r.finish(ErrClosed)
+		return nil, ErrClosed
+	}
+	c.spawn(r.heartbeat)
+
+	return r, nil
+}
+
+func (r *roomStream) Room() string {
+	return r.room
+}
+
+// Done is closed once the stream ended, Err tells why.
+func (r *roomStream) Done() <-chan struct{} {
+	return r.done
+}
+
+// Err is nil when the stream was closed by the client or finished by the server.
+func (r *roomStream) Err() error {
+	<-r.done
+	return r.err
+}
+
+func (r *roomStream) Send(body string) error {
+	trace.SpanFromContext(r.stream.Context()).AddEvent("chat.message.send", trace.WithAttributes(
+		attribute.String("chat.room", r.room),
+		attribute.Int("chat.message.body_size", len(body)),
+	))
+
+	r.sendMu.Lock()
+	defer r.sendMu.Unlock()
+
+	return r.stream.Send(&pb.Message{Body: body})
+}
+
+// Close half-closes the stream, waits for the server to finish it and then
+// releases the stream context.
+func (r *roomStream) Close() error {
+	r.sendMu.Lock()
+	err := r.stream.CloseSend()
+	r.sendMu.Unlock()
+
+	select {
+	case <-r.done:
+	case <-time.After(closeTimeout):
+	}
+	r.finish(nil)
+
+	return err
+}
+
+func (r *roomStream) finish(err error) {
+	r.closeOnce.Do(func() {
+		r.err = err
+		r.cancel()
+		close(r.done)
+	})
+}
+
+func (r *roomStream) receive(deliver func(*pb.Message)) {
+	for {
+		in, err := r.stream.Recv()
+		if errors.Is(err, io.EOF) || status.Code(err) == codes.Canceled {
+			// Сервер завершил стрим или мы сами его закрыли
+			r.finish(nil)
+			return
+		}
+		if err != nil {
+			r.finish(err)
+			return
+		}
+
+		r.lastSeen.Store(time.Now().UnixNano())
+		if in.Type == pb.Message_HEARTBEAT {
+			continue
+		}
+		deliver(in)
+	}
+}
+
+func (r *roomStream) heartbeat() {
+	ticker := time.NewTicker(r.heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+			if idle := time.Since(time.Unix(0, r.lastSeen.Load())); idle > r.idleTimeout {
+				r.logger.With("idle", idle).Error("server heartbeat timeout, closing stream")
+				r.finish(ErrServerIdle)
+				return
+			}
+
+			r.sendMu.Lock()
+			err := r.stream.Send(&pb.Message{Type: pb.Message_HEARTBEAT})
+			r.sendMu.Unlock()
+			if err != nil {
+				r.logger.With(slog.String("error", err.Error())).Error("Failed to send a heartbeat")
+			}
+		}
+	}
+}
@@ -0,0 +1,451 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"grpc-streaming/internal/logging"
+	"grpc-streaming/internal/server/audit"
+	"grpc-streaming/internal/server/history"
+	"grpc-streaming/internal/server/hub"
+	"grpc-streaming/internal/server/interceptors"
+	"grpc-streaming/pkg/server/pipeline"
+	"io"
+	"log/slog"
+	"math"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	pb "grpc-streaming/streaming/grpc"
+)
+
+// defaultHistoryLimit is the number of messages History returns when the
+// request has no limit.
+const defaultHistoryLimit = 20
+
+// Session describes the chat stream a hook is called for.
+type Session struct {
+	StreamID  string
+	Room      string
+	Principal string
+	Peer      string
+}
+
+// JoinHook runs before a stream joins its room, an error rejects the stream
+// and is returned to the client.
+type JoinHook func(ctx context.Context, session Session) error
+
+// MessageHook runs for every message before it is published, msg already
+// carries the sender, room and time. It returns the message to publish, nil
+// to drop it silently, or an error that ends the stream.
+type MessageHook func(ctx context.Context, session Session, msg *pb.Message) (*pb.Message, error)
+
+// LeaveHook runs once a stream left its room, err is why the stream ended.
+type LeaveHook func(ctx context.Context, session Session, err error)
+
+// Feed produces the messages of the Feed RPC, e.g. feed.REST reading them
+// from a REST service. Fetch passes every message to yield as it arrives and
+// returns the error of yield unchanged.
+type Feed interface {
+	Fetch(ctx context.Context, params map[string]string, yield func(*pb.Message) error) error
+}
+
+// errFeedLimit stops a feed once the requested number of messages was sent.
+var errFeedLimit = errors.New("feed limit reached")
+
+type chatService struct {
+	pb.UnimplementedChatServer
+	hub     *hub.Hub
+	history *history.Store
+	audit   audit.Sink
+
+	// heartbeatInterval is how often the server sends a heartbeat frame,
+	// idleTimeout is how long a stream may stay silent before it is closed.
+	heartbeatInterval time.Duration
+	idleTimeout       time.Duration
+	// listenTimeout bounds a gRPC-Web ChatStream after the browser closed its
+	// side, it sends no heartbeats.
+	listenTimeout time.Duration
+
+	onJoin    []JoinHook
+	onMessage []MessageHook
+	onLeave   []LeaveHook
+	// pipeline processes the messages after the hooks, rejections are sent
+	// back to the sender only.
+	pipeline *pipeline.Pipeline
+	feed     Feed
+}
+
+func (s *chatService) ChatStream(stream pb.Chat_ChatStreamServer) (err error) {
+	room := hub.RoomFromContext(stream.Context())
+	ctx := logging.With(stream.Context(), "room", room)
+	logger := logging.FromContext(ctx)
+
+	session := newSession(ctx, room)
+	sub, err := s.join(ctx, session, pb.Chat_ChatStream_FullMethodName)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		s.leave(ctx, session, sub, err)
+	}()
+
+	var lastSeen atomic.Int64
+	lastSeen.Store(time.Now().UnixNano())
+
+	// Чтение идет в отдельной горутине, чтобы медленный получатель не блокировал отправку в хаб.
+	// Ответы только отправителю идут через replies, Send вызывается из одной горутины
+	recvErr := make(chan error, 1)
+	replies := make(chan *pb.Message, 16)
+	go func() {
+		recvErr <- s.receive(stream, logger, session, &lastSeen, replies)
+	}()
+
+	heartbeat := time.NewTicker(s.heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case msg := <-sub.Messages():
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+		case msg := <-replies:
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+		case <-heartbeat.C:
+			if idle := time.Since(time.Unix(0, lastSeen.Load())); idle > s.idleTimeout {
+				logger.With("idle", idle).Warn("client heartbeat timeout, closing stream")
+				return status.Errorf(codes.Unavailable, "no frames received for %s", idle.Round(time.Second))
+			}
+			if err := stream.Send(&pb.Message{Type: pb.Message_HEARTBEAT}); err != nil {
+				return err
+			}
+		case <-sub.Done():
+			return sub.Err()
+		case err := <-recvErr:
+			if err != nil {
+				return err
+			}
+			// Клиент закончил отправку, но ждет уже поставленные в очередь сообщения
+			return flush(stream, sub, replies)
+		}
+	}
+}
+
+// flush sends what is already queued for a stream whose client stopped
+// sending, e.g. the echoes of its last messages.
+func flush(stream pb.Chat_ChatStreamServer, sub *hub.Subscriber, replies <-chan *pb.Message) error {
+	for {
+		var msg *pb.Message
+		select {
+		case msg = <-replies:
+		case msg = <-sub.Messages():
+		default:
+			return nil
+		}
+
+		if err := stream.Send(msg); err != nil {
+			return err
+		}
+	}
+}
+
+// newSession describes the stream of ctx, the interceptors put the ids, the
+// principal and the peer there.
+func newSession(ctx context.Context, room string) Session {
+	return Session{
+		StreamID:  logging.StreamIDFromContext(ctx),
+		Room:      room,
+		Principal: interceptors.PrincipalFromContext(ctx),
+		Peer:      interceptors.PeerIP(ctx),
+	}
+}
+
+// join runs the join hooks and subscribes session to its room, method is
+// what the audit log records.
+func (s *chatService) join(ctx context.Context, session Session, method string) (*hub.Subscriber, error) {
+	for _, hook := range s.onJoin {
+		if err := hook(ctx, session); err != nil {
+			return nil, err
+		}
+	}
+
+	sub := s.hub.Subscribe(ctx, session.Room, hub.Client{
+		StreamID:  session.StreamID,
+		Principal: session.Principal,
+		Peer:      session.Peer,
+	})
+
+	logging.FromContext(ctx).Info("client joined room")
+
+	joinEvent := interceptors.AuditEvent(ctx, audit.RoomJoin, method)
+	joinEvent.Room = session.Room
+	interceptors.Audit(ctx, s.audit, joinEvent)
+
+	return sub, nil
+}
+
+// leave unsubscribes and runs the leave hooks with the error that ended the
+// stream.
+func (s *chatService) leave(ctx context.Context, session Session, sub *hub.Subscriber, err error) {
+	s.hub.Unsubscribe(sub)
+	for _, hook := range s.onLeave {
+		hook(ctx, session, err)
+	}
+}
+
+func (s *chatService) receive(
+	stream pb.Chat_ChatStreamServer,
+	logger *slog.Logger,
+	session Session,
+	lastSeen *atomic.Int64,
+	replies chan<- *pb.Message,
+) error {
+	for {
+		msg, err := stream.Recv()
+		if errors.Is(err, io.EOF) && isGRPCWeb(stream.Context()) {
+			// gRPC-Web не умеет потоковую отправку: браузер сразу закрывает свою
+			// сторону и дальше только читает комнату, без heartbeat-ов, поэтому
+			// время прослушивания ограничено listenTimeout
+			logger.Info("gRPC-Web client listens to the room")
+			lastSeen.Store(math.MaxInt64)
+
+			timer := time.NewTimer(s.listenTimeout)
+			defer timer.Stop()
+			select {
+			case <-stream.Context().Done():
+				return nil
+			case <-timer.C:
+				return status.Errorf(codes.Unavailable, "listened for %s, reconnect to keep listening", s.listenTimeout)
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			logger.Warn("client streaming finished")
+			// Если клиент завершил отправку
+			return nil
+		}
+		if err != nil {
+			logger.With("error", err).Error("[ERROR] client finished with error")
+			return err
+		}
+
+		lastSeen.Store(time.Now().UnixNano())
+		if msg.Type == pb.Message_HEARTBEAT {
+			continue
+		}
+
+		logger.With("body", msg.Body).Info("Received message body from client")
+
+		rejection, err := s.handle(stream.Context(), session, msg.Body)
+		if err != nil {
+			return err
+		}
+		if rejection != nil {
+			select {
+			case replies <- rejection:
+			case <-stream.Context().Done():
+				return stream.Context().Err()
+			}
+		}
+	}
+}
+
+// handle runs a message sent by session through the hooks and the pipeline
+// and publishes the result. A pipeline rejection is returned as the message
+// for the sender, an error of a hook ends the stream.
+func (s *chatService) handle(ctx context.Context, session Session, body string) (*pb.Message, error) {
+	room := session.Room
+	logger := logging.FromContext(ctx)
+
+	span := trace.SpanFromContext(ctx)
+	span.AddEvent("chat.message.received", trace.WithAttributes(
+		attribute.String("chat.room", room),
+		attribute.Int("chat.message.body_size", len(body)),
+	))
+
+	// Рассылаем сообщение всем участникам комнаты, включая отправителя
+	out := &pb.Message{
+		Body:   body,
+		Sender: session.Principal,
+		SentAt: timestamppb.Now(),
+		Room:   room,
+	}
+	var err error
+	for _, hook := range s.onMessage {
+		if out, err = hook(ctx, session, out); err != nil || out == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if out == nil {
+		span.AddEvent("chat.message.dropped", trace.WithAttributes(attribute.String("chat.room", room)))
+		return nil, nil
+	}
+
+	messages := []*pb.Message{out}
+	if s.pipeline != nil {
+		messages, err = s.pipeline.Process(ctx, out)
+		if err != nil {
+			logger.With("error", err).Warn("message rejected")
+			span.AddEvent("chat.message.rejected", trace.WithAttributes(
+				attribute.String("chat.room", room),
+				attribute.String("rpc.grpc.status_code", status.Code(err).String()),
+			))
+			return rejectionMessage(room, err), nil
+		}
+	}
+
+	for _, m := range messages {
+		s.publish(m)
+	}
+	span.AddEvent("chat.message.published", trace.WithAttributes(
+		attribute.String("chat.room", room),
+		attribute.Int("chat.message.count", len(messages)),
+	))
+
+	return nil, nil
+}
+
+// publish delivers msg to its room, processors may move copies to other rooms.
+func (s *chatService) publish(msg *pb.Message) {
+	s.hub.Publish(msg.Room, msg)
+	s.history.Append(history.Entry{
+		Time:      msg.SentAt.AsTime(),
+		Room:      msg.Room,
+		Principal: msg.Sender,
+		Type:      msg.Type,
+		Body:      msg.Body,
+		Metadata:  msg.Metadata,
+	})
+}
+
+// rejectionMessage tells the sender why a message was not published, the
+// status keeps the details of the pipeline error.
+func rejectionMessage(room string, err error) *pb.Message {
+	st := status.Convert(err).Proto()
+
+	return &pb.Message{
+		Type:   pb.Message_ERROR,
+		Body:   st.Message,
+		SentAt: timestamppb.Now(),
+		Room:   room,
+		Status: &pb.Status{Code: st.Code, Message: st.Message, Details: st.Details},
+	}
+}
+
+func (s *chatService) Who(_ context.Context, req *pb.WhoRequest) (*pb.WhoResponse, error) {
+	room := req.Room
+	if room == "" {
+		room = hub.DefaultRoom
+	}
+
+	seen := make(map[string]bool)
+	resp := &pb.WhoResponse{}
+	for _, sub := range s.hub.Subscribers(room) {
+		if sub.Principal == "" || seen[sub.Principal] {
+			continue
+		}
+		seen[sub.Principal] = true
+		resp.Principals = append(resp.Principals, sub.Principal)
+	}
+	sort.Strings(resp.Principals)
+
+	return resp, nil
+}
+
+func (s *chatService) Rooms(context.Context, *pb.RoomsRequest) (*pb.RoomsResponse, error) {
+	participants := make(map[string]map[string]bool)
+	for _, sub := range s.hub.Subscribers("") {
+		if participants[sub.Room] == nil {
+			participants[sub.Room] = make(map[string]bool)
+		}
+		participants[sub.Room][sub.Principal] = true
+	}
+
+	resp := &pb.RoomsResponse{}
+	for _, room := range s.hub.Rooms() {
+		resp.Rooms = append(resp.Rooms, &pb.RoomSummary{Name: room, Participants: int32(len(participants[room]))})
+	}
+
+	return resp, nil
+}
+
+// History returns the last messages of a room, oldest first.
+func (s *chatService) History(_ context.Context, req *pb.HistoryRequest) (*pb.HistoryResponse, error) {
+	if !s.history.Enabled() {
+		return nil, status.Error(codes.FailedPrecondition, "message history is disabled on this server")
+	}
+	if req.Limit < 0 {
+		return nil, status.Error(codes.InvalidArgument, "limit must not be negative")
+	}
+
+	room := req.Room
+	if room == "" {
+		room = hub.DefaultRoom
+	}
+
+	limit := int(req.Limit)
+	if limit == 0 {
+		limit = defaultHistoryLimit
+	}
+
+	entries := s.history.Entries(room, time.Time{})
+	if len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+
+	resp := &pb.HistoryResponse{Messages: make([]*pb.Message, 0, len(entries))}
+	for _, entry := range entries {
+		resp.Messages = append(resp.Messages, entry.Message())
+	}
+
+	return resp, nil
+}
+
+// Feed streams the messages of the configured feed, those without a room
+// get the requested one.
+func (s *chatService) Feed(req *pb.FeedRequest, stream pb.Chat_FeedServer) error {
+	if s.feed == nil {
+		return status.Error(codes.FailedPrecondition, "feed is not configured on this server")
+	}
+	if req.Limit < 0 {
+		return status.Error(codes.InvalidArgument, "limit must not be negative")
+	}
+
+	room := req.Room
+	if room == "" {
+		room = hub.DefaultRoom
+	}
+
+	sent := 0
+	err := s.feed.Fetch(stream.Context(), req.Params, func(msg *pb.Message) error {
+		if msg.Room == "" {
+			msg.Room = room
+		}
+		if msg.SentAt == nil {
+			msg.SentAt = timestamppb.Now()
+		}
+		if err := stream.Send(msg); err != nil {
+			return err
+		}
+
+		if sent++; req.Limit > 0 && sent >= int(req.Limit) {
+			return errFeedLimit
+		}
+		return nil
+	})
+	if errors.Is(err, errFeedLimit) {
+		return nil
+	}
+
+	return err
+}
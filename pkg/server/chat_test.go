@@ -0,0 +1,160 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"grpc-streaming/pkg/server/feed"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+	pb "grpc-streaming/streaming/grpc"
+)
+
+// startServer serves s over an in-memory listener and returns a connected
+// Chat client.
+func startServer(t *testing.T, opts ...Option) pb.ChatClient {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	s, err := New(append([]Option{WithListener(lis)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		_ = s.Serve()
+	}()
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	return pb.NewChatClient(conn)
+}
+
+func TestFeedLimit(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		for i := 0; i < 1000; i++ {
+			if _, err := fmt.Fprintf(w, "{\"body\":\"message %d\"}\n", i); err != nil {
+				return
+			}
+		}
+	}))
+	defer upstream.Close()
+
+	restFeed, err := feed.NewREST(feed.Config{URL: upstream.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := startServer(t, WithFeed(restFeed))
+
+	stream, err := client.Feed(context.Background(), &pb.FeedRequest{Room: "news", Limit: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var msgs []*pb.Message
+	for {
+		msg, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("recv: %v", err)
+		}
+		msgs = append(msgs, msg)
+	}
+
+	if len(msgs) != 3 {
+		t.Fatalf("got %d messages, want 3", len(msgs))
+	}
+	for i, msg := range msgs {
+		if want := fmt.Sprintf("message %d", i); msg.Body != want || msg.Room != "news" || msg.SentAt == nil {
+			t.Errorf("message %d = %v, want body %q in room news with a time", i, msg, want)
+		}
+	}
+}
+
+func TestChatStreamIgnoresForgedGRPCWebHeader(t *testing.T) {
+	client := startServer(t, WithGRPCWebListenTimeout(time.Hour))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ctx = metadata.AppendToOutgoingContext(ctx, "x-grpc-web", "1")
+
+	stream, err := client.ChatStream(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Нативный клиент с заголовком x-grpc-web не слушает комнату бесконечно
+	for {
+		_, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		if err != nil {
+			t.Fatalf("recv: %v, want the stream to end after the half-close", err)
+		}
+	}
+}
+
+func TestGRPCWebChatStreamListenTimeout(t *testing.T) {
+	s, err := New(WithListener(bufconn.Listen(1<<20)), WithGRPCWebListenTimeout(100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Stop)
+
+	web := httptest.NewServer(s.GRPCWeb(http.NotFoundHandler()))
+	defer web.Close()
+
+	// Браузер отправляет запрос без сообщений и сразу закрывает свою сторону
+	req, err := http.NewRequest(http.MethodPost, web.URL+"/streaming.Chat/ChatStream", http.NoBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/grpc-web+proto")
+	req.Header.Set("X-Grpc-Web", "1")
+
+	start := time.Now()
+	resp, err := web.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("the stream ended after %s, before the listen timeout", elapsed)
+	}
+	if !strings.Contains(string(body), "grpc-status:14") && resp.Header.Get("Grpc-Status") != "14" {
+		t.Errorf("response %q, want grpc-status 14", body)
+	}
+}
@@ -0,0 +1,124 @@
+package feed
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Decode reads the elements of a JSON document one at a time and calls fn for
+// each of them, so a large response is never held in memory as a whole. The
+// body is either a JSON array, an object with the array under itemsField, or
+// NDJSON, one value per line. An error of fn stops decoding and is returned.
+func Decode(r io.Reader, itemsField string, fn func(json.RawMessage) error) error {
+	br := bufio.NewReader(r)
+	first, err := firstByte(br)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		return err
+	}
+
+	dec := json.NewDecoder(br)
+	switch {
+	case first == '[':
+		return decodeArray(dec, fn)
+	case first == '{' && itemsField != "":
+		return decodeField(dec, itemsField, fn)
+	default:
+		return decodeStream(dec, fn)
+	}
+}
+
+// firstByte peeks at the first non-space byte without consuming it.
+func firstByte(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+
+		return b, br.UnreadByte()
+	}
+}
+
+// decodeArray walks the array with Token and decodes one element at a time.
+func decodeArray(dec *json.Decoder, fn func(json.RawMessage) error) error {
+	if err := expectDelim(dec, '['); err != nil {
+		return err
+	}
+
+	for dec.More() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return fmt.Errorf("malformed element: %w", err)
+		}
+		if err := fn(raw); err != nil {
+			return err
+		}
+	}
+
+	return expectDelim(dec, ']')
+}
+
+// decodeField skips the members of the top level object up to itemsField and
+// decodes its array. The rest of the object is not read.
+func decodeField(dec *json.Decoder, itemsField string, fn func(json.RawMessage) error) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return fmt.Errorf("malformed object: %w", err)
+		}
+		if key, _ := tok.(string); key == itemsField {
+			return decodeArray(dec, fn)
+		}
+
+		// Соседние поля пропускаются целиком, они обычно небольшие
+		var skip json.RawMessage
+		if err = dec.Decode(&skip); err != nil {
+			return fmt.Errorf("malformed object: %w", err)
+		}
+	}
+
+	return fmt.Errorf("field %q is missing", itemsField)
+}
+
+// decodeStream reads concatenated values, which covers NDJSON.
+func decodeStream(dec *json.Decoder, fn func(json.RawMessage) error) error {
+	for {
+		var raw json.RawMessage
+		err := dec.Decode(&raw)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("malformed line: %w", err)
+		}
+		if err = fn(raw); err != nil {
+			return err
+		}
+	}
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("expected %q: %w", want, err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != want {
+		return fmt.Errorf("expected %q, got %v", want, tok)
+	}
+
+	return nil
+}
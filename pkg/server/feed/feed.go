@@ -0,0 +1,147 @@
+// Package feed streams messages from a REST service: the response, a JSON
+// array or NDJSON, is decoded incrementally and every element is converted
+// to a message as soon as it is read.
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	pb "grpc-streaming/streaming/grpc"
+)
+
+// Config describes the REST service.
+type Config struct {
+	// URL is called with GET, the request params are added to its query
+	// except for the parameters the URL already has.
+	URL string
+	// Token, if set, is sent as bearer authorization.
+	Token string
+	// ItemsField names the array when the response is an object, it is
+	// ignored for NDJSON responses.
+	ItemsField string
+	// Mapping converts the elements, empty means protojson.
+	Mapping Mapping
+	// Timeout bounds a whole call including reading the body, 0 disables it.
+	Timeout time.Duration
+	// Client defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// REST is a feed backed by a REST service.
+type REST struct {
+	cfg    Config
+	url    *url.URL
+	client *http.Client
+}
+
+func NewREST(cfg Config) (*REST, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("feed url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("feed url %q: expected an absolute http or https url", cfg.URL)
+	}
+
+	client := cfg.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &REST{cfg: cfg, url: u, client: client}, nil
+}
+
+// Fetch calls the service and passes every message to yield as soon as it is
+// decoded. An error of yield stops reading and is returned unchanged, the
+// other failures are returned as gRPC statuses.
+func (r *REST) Fetch(ctx context.Context, params map[string]string, yield func(*pb.Message) error) error {
+	if r.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.cfg.Timeout)
+		defer cancel()
+	}
+
+	// Параметры из настроенного URL (ключ API, фильтр арендатора) клиент не переопределяет
+	u := *r.url
+	query := u.Query()
+	for key, value := range params {
+		if !query.Has(key) {
+			query.Set(key, value)
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return status.Errorf(codes.Internal, "feed request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json, application/x-ndjson")
+	if r.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.cfg.Token)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return transportError(ctx, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		return status.Errorf(codes.Unavailable, "feed service responded %s", resp.Status)
+	default:
+		return status.Errorf(codes.FailedPrecondition, "feed service responded %s", resp.Status)
+	}
+
+	// Объект в NDJSON это очередной элемент, а не обертка с массивом
+	itemsField := r.cfg.ItemsField
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); strings.HasSuffix(mediaType, "ndjson") {
+		itemsField = ""
+	}
+
+	// Ошибку yield нужно вернуть как есть, поэтому она запоминается отдельно
+	var yieldErr error
+	n := 0
+	err = Decode(resp.Body, itemsField, func(raw json.RawMessage) error {
+		msg, err := r.cfg.Mapping.Message(raw)
+		if err != nil {
+			return fmt.Errorf("element %d: %w", n, err)
+		}
+		n++
+
+		yieldErr = yield(msg)
+		return yieldErr
+	})
+	switch {
+	case err == nil:
+		return nil
+	case yieldErr != nil:
+		return yieldErr
+	case ctx.Err() != nil:
+		return transportError(ctx, err)
+	}
+
+	return status.Errorf(codes.Internal, "malformed feed response: %v", err)
+}
+
+func transportError(ctx context.Context, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return status.Error(codes.DeadlineExceeded, "feed service did not respond in time")
+	}
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return status.FromContextError(ctx.Err()).Err()
+	}
+
+	return status.Errorf(codes.Unavailable, "feed service: %v", err)
+}
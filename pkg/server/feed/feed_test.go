@@ -0,0 +1,267 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	pb "grpc-streaming/streaming/grpc"
+)
+
+// newFeed starts an httptest stand-in of the REST service.
+func newFeed(t *testing.T, cfg Config, handler http.HandlerFunc) *REST {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	if cfg.URL == "" {
+		cfg.URL = srv.URL + "/messages"
+	} else {
+		cfg.URL = srv.URL + cfg.URL
+	}
+	f, err := NewREST(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return f
+}
+
+func fetchAll(ctx context.Context, f *REST, params map[string]string) ([]*pb.Message, error) {
+	var msgs []*pb.Message
+	err := f.Fetch(ctx, params, func(msg *pb.Message) error {
+		msgs = append(msgs, msg)
+		return nil
+	})
+
+	return msgs, err
+}
+
+func TestFetchLargeArray(t *testing.T) {
+	const n = 20000
+	f := newFeed(t, Config{}, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, "[")
+		for i := 0; i < n; i++ {
+			if i > 0 {
+				_, _ = io.WriteString(w, ",")
+			}
+			fmt.Fprintf(w, `{"body":"message %d","sender":"feed"}`, i)
+		}
+		_, _ = io.WriteString(w, "]")
+	})
+
+	msgs, err := fetchAll(context.Background(), f, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != n {
+		t.Fatalf("got %d messages, want %d", len(msgs), n)
+	}
+	if msgs[0].Body != "message 0" || msgs[n-1].Body != fmt.Sprintf("message %d", n-1) || msgs[n-1].Sender != "feed" {
+		t.Errorf("unexpected messages: first %v, last %v", msgs[0], msgs[n-1])
+	}
+}
+
+func TestFetchItemsField(t *testing.T) {
+	f := newFeed(t, Config{
+		ItemsField: "items",
+		Mapping:    Mapping{"body": "text", "sender": "user.name"},
+	}, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"total": 2, "meta": {"items": "not these"}, "items": [
+			{"text": "first", "user": {"name": "alice"}},
+			{"text": "second", "user": {"name": "bob"}}
+		], "next": null}`)
+	})
+
+	msgs, err := fetchAll(context.Background(), f, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 2 || msgs[0].Body != "first" || msgs[0].Sender != "alice" || msgs[1].Sender != "bob" {
+		t.Errorf("unexpected messages: %v", msgs)
+	}
+}
+
+func TestFetchNDJSON(t *testing.T) {
+	// items_field не применяется к NDJSON: каждый объект это элемент
+	f := newFeed(t, Config{ItemsField: "items"}, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		_, _ = io.WriteString(w, "{\"body\":\"one\"}\n{\"body\":\"two\"}\n\n{\"body\":\"three\"}\n")
+	})
+
+	msgs, err := fetchAll(context.Background(), f, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 3 || msgs[2].Body != "three" {
+		t.Errorf("unexpected messages: %v", msgs)
+	}
+}
+
+func TestFetchMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"element is not a message", `[{"body":"ok"},{"body":42}]`, 1},
+		{"truncated array", `[{"body":"ok"},{"body":"cut`, 1},
+		{"not json", `<html>`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFeed(t, Config{}, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = io.WriteString(w, tt.body)
+			})
+
+			msgs, err := fetchAll(context.Background(), f, nil)
+			if status.Code(err) != codes.Internal {
+				t.Errorf("err = %v, want Internal", err)
+			}
+			if len(msgs) != tt.want {
+				t.Errorf("got %d messages before the error, want %d", len(msgs), tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchUpstreamStatus(t *testing.T) {
+	tests := []struct {
+		code int
+		want codes.Code
+	}{
+		{http.StatusInternalServerError, codes.Unavailable},
+		{http.StatusBadGateway, codes.Unavailable},
+		{http.StatusTooManyRequests, codes.Unavailable},
+		{http.StatusNotFound, codes.FailedPrecondition},
+		{http.StatusUnauthorized, codes.FailedPrecondition},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.code), func(t *testing.T) {
+			f := newFeed(t, Config{}, func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "upstream failure", tt.code)
+			})
+
+			if _, err := fetchAll(context.Background(), f, nil); status.Code(err) != tt.want {
+				t.Errorf("err = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchStopsOnYieldError(t *testing.T) {
+	f := newFeed(t, Config{}, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `[{"body":"1"},{"body":"2"},{"body":"3"},{"body":"4"}]`)
+	})
+
+	errLimit := errors.New("limit reached")
+	n := 0
+	err := f.Fetch(context.Background(), nil, func(*pb.Message) error {
+		if n++; n == 2 {
+			return errLimit
+		}
+		return nil
+	})
+	if !errors.Is(err, errLimit) || n != 2 {
+		t.Errorf("err = %v after %d messages, want the yield error after 2", err, n)
+	}
+}
+
+func TestFetchCancelMidBody(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	f := newFeed(t, Config{}, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `[{"body":"first"},`)
+		w.(http.Flusher).Flush()
+
+		// Остаток тела не приходит, пока клиент не уйдет
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	got := make(chan *pb.Message, 1)
+	go func() {
+		done <- f.Fetch(ctx, nil, func(msg *pb.Message) error {
+			got <- msg
+			return nil
+		})
+	}()
+
+	select {
+	case msg := <-got:
+		if msg.Body != "first" {
+			t.Fatalf("first message = %v", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the first element was not yielded before the body ended")
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if status.Code(err) != codes.Canceled {
+			t.Errorf("err = %v, want Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Fetch did not return after the cancellation")
+	}
+}
+
+func TestFetchTimeout(t *testing.T) {
+	f := newFeed(t, Config{Timeout: 50 * time.Millisecond}, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `[`)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+
+	if _, err := fetchAll(context.Background(), f, nil); status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("err = %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestFetchRequest(t *testing.T) {
+	f := newFeed(t, Config{URL: "/messages?api_key=secret&tenant=a", Token: "feed-token"}, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		switch {
+		case r.Header.Get("Authorization") != "Bearer feed-token":
+			http.Error(w, "no token", http.StatusUnauthorized)
+		case query.Get("api_key") != "secret" || query.Get("tenant") != "a":
+			http.Error(w, "configured parameters overridden: "+r.URL.RawQuery, http.StatusForbidden)
+		default:
+			_, _ = fmt.Fprintf(w, `[{"body":%q}]`, query.Get("q"))
+		}
+	})
+
+	msgs, err := fetchAll(context.Background(), f, map[string]string{"q": "news", "api_key": "mine", "tenant": "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 || msgs[0].Body != "news" {
+		t.Errorf("unexpected messages: %v", msgs)
+	}
+}
+
+func TestNewRESTRejectsRelativeURL(t *testing.T) {
+	if _, err := NewREST(Config{URL: "/messages"}); err == nil || !strings.Contains(err.Error(), "absolute") {
+		t.Errorf("err = %v, want an absolute url error", err)
+	}
+}
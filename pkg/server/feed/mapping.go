@@ -0,0 +1,140 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	pb "grpc-streaming/streaming/grpc"
+)
+
+// Mapping tells where the fields of a message are found in a JSON element,
+// e.g. {"body": "text", "sender": "user.name"}. The keys are body, sender,
+// room, sent_at and metadata.<name>, the values are dot separated paths.
+// An empty mapping decodes the elements as protojson messages instead.
+type Mapping map[string]string
+
+// ParseMapping parses "target=path" pairs.
+func ParseMapping(pairs []string) (Mapping, error) {
+	m := make(Mapping, len(pairs))
+	for _, pair := range pairs {
+		target, source, ok := strings.Cut(pair, "=")
+		target, source = strings.TrimSpace(target), strings.TrimSpace(source)
+		if !ok || target == "" || source == "" {
+			return nil, fmt.Errorf("mapping %q: expected target=path", pair)
+		}
+
+		switch {
+		case target == "body", target == "sender", target == "room", target == "sent_at":
+		case strings.HasPrefix(target, "metadata.") && len(target) > len("metadata."):
+		default:
+			return nil, fmt.Errorf("mapping %q: unknown target %q", pair, target)
+		}
+		m[target] = source
+	}
+
+	return m, nil
+}
+
+var unmarshalOptions = protojson.UnmarshalOptions{DiscardUnknown: true}
+
+// Message converts one element. Missing paths leave the field empty.
+func (m Mapping) Message(raw json.RawMessage) (*pb.Message, error) {
+	msg := &pb.Message{}
+	if len(m) == 0 {
+		if err := unmarshalOptions.Unmarshal(raw, msg); err != nil {
+			return nil, err
+		}
+		return msg, nil
+	}
+
+	var element any
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&element); err != nil {
+		return nil, err
+	}
+
+	for target, source := range m {
+		value, ok := lookup(element, source)
+		if !ok {
+			continue
+		}
+
+		switch target {
+		case "body":
+			msg.Body = text(value)
+		case "sender":
+			msg.Sender = text(value)
+		case "room":
+			msg.Room = text(value)
+		case "sent_at":
+			sentAt, err := timestamp(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", source, err)
+			}
+			msg.SentAt = sentAt
+		default:
+			if msg.Metadata == nil {
+				msg.Metadata = make(map[string]string)
+			}
+			msg.Metadata[strings.TrimPrefix(target, "metadata.")] = text(value)
+		}
+	}
+
+	return msg, nil
+}
+
+func lookup(element any, path string) (any, bool) {
+	for _, key := range strings.Split(path, ".") {
+		object, ok := element.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if element, ok = object[key]; !ok {
+			return nil, false
+		}
+	}
+
+	return element, element != nil
+}
+
+// text renders scalars as is and everything else as JSON.
+func text(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+// timestamp accepts RFC 3339 strings and unix seconds.
+func timestamp(value any) (*timestamppb.Timestamp, error) {
+	switch v := value.(type) {
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return nil, err
+		}
+		return timestamppb.New(t), nil
+	case json.Number:
+		seconds, err := v.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return timestamppb.New(time.UnixMilli(int64(seconds * 1000))), nil
+	}
+
+	return nil, fmt.Errorf("expected an RFC 3339 string or unix seconds, got %s", text(value))
+}
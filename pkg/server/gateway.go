@@ -0,0 +1,300 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"grpc-streaming/internal/logging"
+	"grpc-streaming/internal/server/hub"
+	"grpc-streaming/internal/server/interceptors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	pb "grpc-streaming/streaming/grpc"
+)
+
+// maxGatewayBodySize bounds a posted message like the default gRPC limit.
+const maxGatewayBodySize = 64 << 10
+
+// Limiter charges a message to its sender, an error refuses it. The rate
+// limit interceptor of cmd/server is one.
+type Limiter interface {
+	Allow(ctx context.Context, method string, msg any) error
+}
+
+// StreamLimiter admits a long-lived stream of method, the returned release is
+// called once it ends. The stream limit interceptor of cmd/server is one.
+type StreamLimiter interface {
+	Admit(ctx context.Context, method string) (release func(), err error)
+}
+
+// gateway serves the Chat service over HTTP with protojson bodies. The calls
+// are authorized like ChatStream and go through the same hooks, pipeline and
+// hub, but not through the gRPC interceptors.
+type gateway struct {
+	chat     *chatService
+	auth     *interceptors.AuthServerInterceptor
+	limiter  Limiter
+	streams  StreamLimiter
+	upgrader websocket.Upgrader
+
+	quit     chan struct{}
+	quitOnce sync.Once
+}
+
+func (g *gateway) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/rooms/{room}/messages", g.send)
+	mux.HandleFunc("GET /v1/rooms/{room}/messages", g.stream)
+	mux.HandleFunc("GET /v1/rooms/{room}/ws", g.webSocket)
+
+	return mux
+}
+
+// close ends the open streams, the server is going away.
+func (g *gateway) close() {
+	g.quitOnce.Do(func() {
+		close(g.quit)
+	})
+}
+
+// authorize checks the bearer token of r against ChatStream's roles.
+func (g *gateway) authorize(w http.ResponseWriter, r *http.Request, room string) (context.Context, bool) {
+	ctx := interceptors.HTTPContext(r, pb.Chat_ChatStream_FullMethodName, room)
+	w.Header().Set(logging.RequestIDMetadataKey, logging.RequestIDFromContext(ctx))
+
+	ctx, err := g.auth.Authorize(ctx, pb.Chat_ChatStream_FullMethodName)
+	if err != nil {
+		writeStatus(w, err)
+		return nil, false
+	}
+
+	return logging.With(ctx, "room", room), true
+}
+
+// join admits a stream past the stream limits, which the gRPC interceptors
+// apply to ChatStream, and subscribes it to its room. release has to be
+// called after leave.
+func (g *gateway) join(ctx context.Context, session Session) (*hub.Subscriber, func(), error) {
+	release := func() {}
+	if g.streams != nil {
+		var err error
+		if release, err = g.streams.Admit(ctx, pb.Chat_ChatStream_FullMethodName); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	sub, err := g.chat.join(ctx, session, pb.Chat_ChatStream_FullMethodName)
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+
+	return sub, release, nil
+}
+
+// send publishes the body of the posted CHAT Message to the room. It answers
+// 204, 400 for other message types, or 422 with the ERROR message when the
+// pipeline rejects it.
+func (g *gateway) send(w http.ResponseWriter, r *http.Request) {
+	room := r.PathValue("room")
+	ctx, ok := g.authorize(w, r, room)
+	if !ok {
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxGatewayBodySize))
+	if err != nil {
+		writeStatus(w, status.Errorf(codes.InvalidArgument, "cannot read the body: %v", err))
+		return
+	}
+
+	msg := &pb.Message{}
+	if err = protojson.Unmarshal(data, msg); err != nil {
+		writeStatus(w, status.Errorf(codes.InvalidArgument, "body is not a Message: %v", err))
+		return
+	}
+	// Лимитер пропускает heartbeat-ы бесплатно, публиковать можно только CHAT
+	if msg.Type != pb.Message_CHAT {
+		writeStatus(w, status.Errorf(codes.InvalidArgument, "only %s messages can be posted, got %s", pb.Message_CHAT, msg.Type))
+		return
+	}
+
+	if g.limiter != nil {
+		if err = g.limiter.Allow(ctx, pb.Chat_ChatStream_FullMethodName, msg); err != nil {
+			writeStatus(w, err)
+			return
+		}
+	}
+
+	rejection, err := g.chat.handle(ctx, newSession(ctx, room), msg.Body)
+	switch {
+	case err != nil:
+		writeStatus(w, err)
+	case rejection != nil:
+		writeMessage(w, http.StatusUnprocessableEntity, rejection)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// stream joins the room and writes its messages as Server-Sent Events when
+// the client accepts them, as NDJSON otherwise. The error that ends the
+// stream is written as a last ERROR message.
+func (g *gateway) stream(w http.ResponseWriter, r *http.Request) {
+	room := r.PathValue("room")
+	ctx, ok := g.authorize(w, r, room)
+	if !ok {
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeStatus(w, status.Error(codes.Unimplemented, "streaming is not supported by this connection"))
+		return
+	}
+
+	var write func(*pb.Message) error
+	if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		w.Header().Set("Content-Type", "text/event-stream")
+		write = func(msg *pb.Message) error {
+			return writeEvent(w, msg)
+		}
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		write = func(msg *pb.Message) error {
+			return writeLine(w, msg)
+		}
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+
+	session := newSession(ctx, room)
+	sub, release, err := g.join(ctx, session)
+	if err != nil {
+		writeStatus(w, err)
+		return
+	}
+	defer func() {
+		g.chat.leave(ctx, session, sub, err)
+		release()
+	}()
+
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(g.chat.heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for err == nil {
+		select {
+		case msg := <-sub.Messages():
+			err = write(msg)
+		case <-heartbeat.C:
+			err = write(&pb.Message{Type: pb.Message_HEARTBEAT})
+		case <-sub.Done():
+			err = sub.Err()
+			if err == nil {
+				err = status.Error(codes.Aborted, "stream closed by the server")
+			}
+			_ = write(rejectionMessage(room, err))
+		case <-g.quit:
+			err = status.Error(codes.Unavailable, "server is shutting down")
+			_ = write(rejectionMessage(room, err))
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		flusher.Flush()
+	}
+
+	logging.FromContext(ctx).With("error", err).Info("gateway stream finished")
+}
+
+var marshalOptions = protojson.MarshalOptions{}
+
+// writeEvent writes an SSE message event, the type is in the data like in the
+// NDJSON lines. Heartbeats are comments EventSource ignores.
+func writeEvent(w io.Writer, msg *pb.Message) error {
+	if msg.Type == pb.Message_HEARTBEAT {
+		_, err := io.WriteString(w, ": heartbeat\n\n")
+		return err
+	}
+
+	data, err := marshalOptions.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+	return err
+}
+
+func writeLine(w io.Writer, msg *pb.Message) error {
+	data, err := marshalOptions.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+func writeMessage(w http.ResponseWriter, code int, msg proto.Message) {
+	data, err := marshalOptions.Marshal(msg)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, _ = w.Write(data)
+}
+
+// writeStatus answers with the HTTP equivalent of the gRPC code and the
+// status, details included, as a google.rpc.Status shaped body.
+func writeStatus(w http.ResponseWriter, err error) {
+	st := status.Convert(err).Proto()
+	if errors.Is(err, context.Canceled) {
+		st = status.FromContextError(err).Proto()
+	}
+
+	writeMessage(w, httpStatus(codes.Code(st.Code)), &pb.Status{Code: st.Code, Message: st.Message, Details: st.Details})
+}
+
+// httpStatus maps gRPC codes like the gRPC-HTTP mapping of google.rpc.Code.
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.OutOfRange, codes.FailedPrecondition:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	}
+
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestGatewaySendAcceptsOnlyChat(t *testing.T) {
+	s, err := New(WithListener(bufconn.Listen(1 << 20)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Stop)
+
+	gw := httptest.NewServer(s.Gateway())
+	defer gw.Close()
+
+	tests := []struct {
+		body string
+		want int
+	}{
+		{`{"body":"hi"}`, http.StatusNoContent},
+		{`{"type":"CHAT","body":"hi"}`, http.StatusNoContent},
+		{`{"type":"HEARTBEAT","body":"past the limiter"}`, http.StatusBadRequest},
+		{`{"type":"SYSTEM","body":"fake notice"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		resp, err := gw.Client().Post(gw.URL+"/v1/rooms/general/messages", "application/json", strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("POST %s = %d, want %d", tt.body, resp.StatusCode, tt.want)
+		}
+	}
+
+	if n := s.History().Len()["general"]; n != 2 {
+		t.Errorf("history has %d messages, want the 2 chat messages", n)
+	}
+}
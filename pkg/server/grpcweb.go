@@ -0,0 +1,38 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/improbable-eng/grpc-web/go/grpcweb"
+)
+
+// GRPCWeb serves the registered gRPC services to gRPC-Web clients, binary and
+// text mode, and passes every other request to next, e.g. Gateway(). CORS
+// lets the browser pages of origins call them, "*" every page. The calls go
+// through the interceptors like any other, ChatStream included: browsers
+// can't stream to the server, they open it with at most one message and keep
+// reading the room, for the WithGRPCWebListenTimeout at most. Call it once
+// every service is registered.
+func (s *Server) GRPCWeb(next http.Handler, origins ...string) http.Handler {
+	web := grpcweb.WrapServer(s.grpc, grpcweb.WithOriginFunc(allowOrigin(origins)))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if web.IsGrpcWebRequest(r) || web.IsAcceptableGrpcCorsRequest(r) {
+			web.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), grpcWebKey{}, true)))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// grpcWebKey marks the context of the calls served by GRPCWeb. Unlike the
+// x-grpc-web header a native gRPC client cannot set it.
+type grpcWebKey struct{}
+
+// isGRPCWeb tells the calls of the gRPC-Web clients apart.
+func isGRPCWeb(ctx context.Context) bool {
+	web, _ := ctx.Value(grpcWebKey{}).(bool)
+	return web
+}
@@ -0,0 +1,286 @@
+package server
+
+import (
+	"crypto/tls"
+	"errors"
+	"grpc-streaming/internal/server/audit"
+	"grpc-streaming/internal/server/hub"
+	"grpc-streaming/internal/server/interceptors"
+	"grpc-streaming/pkg/server/pipeline"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+const (
+	defaultAddress           = ":50051"
+	defaultQueueSize         = 64
+	defaultHistorySize       = 100
+	defaultHeartbeatInterval = 15 * time.Second
+	defaultIdleTimeout       = 45 * time.Second
+	defaultListenTimeout     = 30 * time.Minute
+)
+
+// Identity is what a bearer token resolves to.
+type Identity = interceptors.Identity
+
+// Observer is notified of rejected calls, e.g. to count them.
+type Observer = interceptors.Observer
+
+// AuditSink persists security relevant events.
+type AuditSink = audit.Sink
+
+// AuditEvent is what an AuditSink records.
+type AuditEvent = audit.Event
+
+// Policy decides what happens when a subscriber's outbound queue is full.
+type Policy = hub.Policy
+
+const (
+	DropOldest = hub.DropOldest
+	DropNewest = hub.DropNewest
+	Disconnect = hub.Disconnect
+)
+
+// Option configures a Server.
+type Option func(*options)
+
+type options struct {
+	address  string
+	listener net.Listener
+	creds    credentials.TransportCredentials
+
+	auth     bool
+	tokens   map[string]Identity
+	observer Observer
+	audit    AuditSink
+
+	unaryInterceptors  []grpc.UnaryServerInterceptor
+	streamInterceptors []grpc.StreamServerInterceptor
+	serverOptions      []grpc.ServerOption
+
+	queueSize   int
+	policy      Policy
+	historySize int
+
+	heartbeatInterval time.Duration
+	idleTimeout       time.Duration
+	listenTimeout     time.Duration
+
+	onJoin    []JoinHook
+	onMessage []MessageHook
+	onLeave   []LeaveHook
+	pipeline  *pipeline.Pipeline
+	feed      Feed
+
+	gatewayLimiter   Limiter
+	gatewayStreams   StreamLimiter
+	webSocketOrigins []string
+}
+
+func defaultOptions() *options {
+	return &options{
+		address:           defaultAddress,
+		audit:             audit.NopSink(),
+		queueSize:         defaultQueueSize,
+		policy:            DropOldest,
+		historySize:       defaultHistorySize,
+		heartbeatInterval: defaultHeartbeatInterval,
+		idleTimeout:       defaultIdleTimeout,
+		listenTimeout:     defaultListenTimeout,
+	}
+}
+
+// validate checks the options New cannot work with: the tickers and timers
+// of the streams need positive durations.
+func (o *options) validate() error {
+	var errs []error
+	if o.heartbeatInterval <= 0 {
+		errs = append(errs, errors.New("heartbeat interval must be positive"))
+	}
+	if o.idleTimeout <= 0 {
+		errs = append(errs, errors.New("idle timeout must be positive"))
+	}
+	if o.listenTimeout <= 0 {
+		errs = append(errs, errors.New("gRPC-Web listen timeout must be positive"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// WithAddress sets the TCP address New listens on, ":50051" by default.
+func WithAddress(address string) Option {
+	return func(o *options) {
+		o.address = address
+	}
+}
+
+// WithListener serves on lis instead of listening on the address.
+func WithListener(lis net.Listener) Option {
+	return func(o *options) {
+		o.listener = lis
+	}
+}
+
+// WithTLS serves over TLS, set ClientAuth in cfg for mutual TLS.
+func WithTLS(cfg *tls.Config) Option {
+	return func(o *options) {
+		o.creds = credentials.NewTLS(cfg)
+	}
+}
+
+// WithTransportCredentials replaces the default plaintext transport.
+func WithTransportCredentials(creds credentials.TransportCredentials) Option {
+	return func(o *options) {
+		o.creds = creds
+	}
+}
+
+// WithAuth requires a bearer token with the "user" or "admin" role, the Admin
+// service and reflection need "admin". With nil tokens every token is
+// accepted as a "user". Health checks stay public.
+func WithAuth(tokens map[string]Identity) Option {
+	return func(o *options) {
+		o.auth = true
+		o.tokens = tokens
+	}
+}
+
+// WithAuthObserver is notified of authentication failures and denials.
+func WithAuthObserver(observer Observer) Option {
+	return func(o *options) {
+		o.observer = observer
+	}
+}
+
+// WithAudit records stream lifecycle, joins and auth failures to sink.
+func WithAudit(sink AuditSink) Option {
+	return func(o *options) {
+		o.audit = sink
+	}
+}
+
+// WithUnaryInterceptors appends interceptors after the request id and auth ones.
+func WithUnaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) Option {
+	return func(o *options) {
+		o.unaryInterceptors = append(o.unaryInterceptors, interceptors...)
+	}
+}
+
+// WithStreamInterceptors appends interceptors after the request id, auth and
+// audit ones.
+func WithStreamInterceptors(interceptors ...grpc.StreamServerInterceptor) Option {
+	return func(o *options) {
+		o.streamInterceptors = append(o.streamInterceptors, interceptors...)
+	}
+}
+
+// WithServerOptions appends raw gRPC server options, e.g. stats handlers,
+// message size limits or keepalive settings.
+func WithServerOptions(opts ...grpc.ServerOption) Option {
+	return func(o *options) {
+		o.serverOptions = append(o.serverOptions, opts...)
+	}
+}
+
+// WithHub sets the outbound queue size of every subscriber and what happens
+// when it is full.
+func WithHub(queueSize int, policy Policy) Option {
+	return func(o *options) {
+		o.queueSize = queueSize
+		o.policy = policy
+	}
+}
+
+// WithHistorySize sets how many messages are kept per room for History, 0
+// disables the history.
+func WithHistorySize(size int) Option {
+	return func(o *options) {
+		o.historySize = size
+	}
+}
+
+// WithHeartbeat sets how often a heartbeat frame is sent and how long a
+// stream may stay silent before it is closed, New rejects values that are not
+// positive.
+func WithHeartbeat(interval, idleTimeout time.Duration) Option {
+	return func(o *options) {
+		o.heartbeatInterval = interval
+		o.idleTimeout = idleTimeout
+	}
+}
+
+// WithGRPCWebListenTimeout bounds how long a gRPC-Web ChatStream keeps
+// reading the room after the browser closed its side, it cannot send
+// heartbeats. The client has to reconnect afterwards, 30m by default.
+func WithGRPCWebListenTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.listenTimeout = timeout
+	}
+}
+
+// WithOnJoin adds a hook called before a stream joins its room.
+func WithOnJoin(hook JoinHook) Option {
+	return func(o *options) {
+		o.onJoin = append(o.onJoin, hook)
+	}
+}
+
+// WithOnMessage adds a hook called for every message before it is published.
+func WithOnMessage(hook MessageHook) Option {
+	return func(o *options) {
+		o.onMessage = append(o.onMessage, hook)
+	}
+}
+
+// WithOnLeave adds a hook called once a stream left its room.
+func WithOnLeave(hook LeaveHook) Option {
+	return func(o *options) {
+		o.onLeave = append(o.onLeave, hook)
+	}
+}
+
+// WithPipeline runs every message through the processors of its room after
+// the message hooks. A rejected message is not published, the sender gets
+// an ERROR message carrying the status and its details instead.
+func WithPipeline(p *pipeline.Pipeline) Option {
+	return func(o *options) {
+		o.pipeline = p
+	}
+}
+
+// WithFeed serves the Feed RPC from f, without it the RPC fails with
+// FailedPrecondition.
+func WithFeed(f Feed) Option {
+	return func(o *options) {
+		o.feed = f
+	}
+}
+
+// WithGatewayLimiter checks every message posted to the HTTP gateway with l,
+// e.g. the rate limit interceptor given to WithStreamInterceptors.
+func WithGatewayLimiter(l Limiter) Option {
+	return func(o *options) {
+		o.gatewayLimiter = l
+	}
+}
+
+// WithGatewayStreamLimiter admits every SSE, NDJSON and WebSocket stream of
+// the HTTP gateway with l, e.g. the stream limit interceptor given to
+// WithStreamInterceptors, so gateway streams count against the same limits.
+func WithGatewayStreamLimiter(l StreamLimiter) Option {
+	return func(o *options) {
+		o.gatewayStreams = l
+	}
+}
+
+// WithWebSocketOrigins lets browser pages of other origins, e.g.
+// "https://chat.example.com", open gateway WebSockets, "*" lets every page.
+// By default only pages of the gateway's own origin may.
+func WithWebSocketOrigins(origins ...string) Option {
+	return func(o *options) {
+		o.webSocketOrigins = append(o.webSocketOrigins, origins...)
+	}
+}
@@ -0,0 +1,121 @@
+package pipeline
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the YAML form of a pipeline:
+//
+//	rooms:
+//	  - match: "support-*"
+//	    processors:
+//	      - type: length
+//	        max: 500
+//	      - type: profanity
+//	        words: [darn, heck]
+//	        action: mask
+//	      - type: links
+//	      - type: copy_to
+//	        rooms: [moderation]
+//	  - match: "*"
+//	    processors:
+//	      - type: length
+//	        max: 2000
+type Config struct {
+	Rooms []RoomConfig `yaml:"rooms"`
+}
+
+type RoomConfig struct {
+	Match      string            `yaml:"match"`
+	Processors []ProcessorConfig `yaml:"processors"`
+}
+
+// ProcessorConfig holds the settings of every built-in processor, Type picks
+// the one that is used.
+type ProcessorConfig struct {
+	// Type is length, profanity, links or copy_to.
+	Type string `yaml:"type"`
+	// Min and Max bound the length in characters.
+	Min int `yaml:"min"`
+	Max int `yaml:"max"`
+	// Words and Action, reject or mask, configure the profanity filter.
+	Words  []string `yaml:"words"`
+	Action string   `yaml:"action"`
+	// Rooms are the copy_to targets.
+	Rooms []string `yaml:"rooms"`
+}
+
+// Load reads a pipeline file, unknown keys are rejected.
+func Load(filePath string) (*Pipeline, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err = decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("pipeline file %s: %w", filePath, err)
+	}
+
+	p, err := cfg.Build()
+	if err != nil {
+		return nil, fmt.Errorf("pipeline file %s: %w", filePath, err)
+	}
+
+	return p, nil
+}
+
+// Build creates the pipeline with the built-in processors.
+func (c Config) Build() (*Pipeline, error) {
+	p := New()
+	for i, room := range c.Rooms {
+		if _, err := path.Match(room.Match, ""); err != nil || room.Match == "" {
+			return nil, fmt.Errorf("rooms[%d]: invalid match pattern %q", i, room.Match)
+		}
+
+		processors := make([]Processor, 0, len(room.Processors))
+		for j, pc := range room.Processors {
+			processor, err := pc.build()
+			if err != nil {
+				return nil, fmt.Errorf("rooms[%d].processors[%d]: %w", i, j, err)
+			}
+			processors = append(processors, processor)
+		}
+		p.Handle(room.Match, processors...)
+	}
+
+	return p, nil
+}
+
+func (c ProcessorConfig) build() (Processor, error) {
+	switch c.Type {
+	case "length":
+		if c.Min < 0 || c.Max < 0 || (c.Max > 0 && c.Min > c.Max) {
+			return nil, errors.New("length: min and max must not be negative and min must not exceed max")
+		}
+		return Length(c.Min, c.Max), nil
+	case "profanity":
+		if c.Action != "" && c.Action != "reject" && c.Action != "mask" {
+			return nil, fmt.Errorf("profanity: unknown action %q", c.Action)
+		}
+		return Profanity(c.Words, c.Action == "mask"), nil
+	case "links":
+		return Links(), nil
+	case "copy_to":
+		if len(c.Rooms) == 0 {
+			return nil, errors.New("copy_to: rooms are required")
+		}
+		return CopyTo(c.Rooms...), nil
+	}
+
+	return nil, fmt.Errorf("unknown processor type %q", c.Type)
+}
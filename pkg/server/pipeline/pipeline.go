@@ -0,0 +1,146 @@
+// Package pipeline runs the messages of a room through an ordered chain of
+// processors before they are published. A processor may validate, transform,
+// enrich, reject or fan a message out to several messages.
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"grpc-streaming/internal/logging"
+	"path"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	pb "grpc-streaming/streaming/grpc"
+)
+
+// Domain is the ErrorInfo domain of the rejections.
+const Domain = "chat.pipeline"
+
+// Processor handles a message on its way to the room. It returns the
+// messages to pass on: the message itself, a changed copy, none to drop it
+// or several to fan it out, e.g. to other rooms. An error rejects the message.
+type Processor interface {
+	Name() string
+	Process(ctx context.Context, msg *pb.Message) ([]*pb.Message, error)
+}
+
+type funcProcessor struct {
+	name string
+	fn   func(ctx context.Context, msg *pb.Message) ([]*pb.Message, error)
+}
+
+// Func turns fn into a Processor.
+func Func(name string, fn func(ctx context.Context, msg *pb.Message) ([]*pb.Message, error)) Processor {
+	return &funcProcessor{name: name, fn: fn}
+}
+
+func (p *funcProcessor) Name() string {
+	return p.name
+}
+
+func (p *funcProcessor) Process(ctx context.Context, msg *pb.Message) ([]*pb.Message, error) {
+	return p.fn(ctx, msg)
+}
+
+// Rejection is a processor refusing a message, it is reported to the sender.
+type Rejection struct {
+	Code codes.Code
+	// Reason is a short UPPER_SNAKE_CASE identifier, e.g. MESSAGE_TOO_LONG.
+	Reason  string
+	Message string
+}
+
+func (r *Rejection) Error() string {
+	return r.Message
+}
+
+// Reject refuses a message with codes.InvalidArgument.
+func Reject(reason, format string, args ...any) error {
+	return &Rejection{Code: codes.InvalidArgument, Reason: reason, Message: fmt.Sprintf(format, args...)}
+}
+
+type route struct {
+	pattern    string
+	processors []Processor
+}
+
+// Pipeline picks the chain of the first route whose pattern matches the room.
+// Patterns use path.Match syntax, "*" matches every room.
+type Pipeline struct {
+	routes []route
+}
+
+func New() *Pipeline {
+	return &Pipeline{}
+}
+
+// Handle adds a route, routes are matched in the order they were added.
+func (p *Pipeline) Handle(pattern string, processors ...Processor) *Pipeline {
+	p.routes = append(p.routes, route{pattern: pattern, processors: processors})
+	return p
+}
+
+func (p *Pipeline) chain(room string) []Processor {
+	for _, r := range p.routes {
+		if ok, _ := path.Match(r.pattern, room); ok {
+			return r.processors
+		}
+	}
+
+	return nil
+}
+
+// Process runs msg through the chain of its room. Every message a processor
+// returns goes through the rest of the chain. The error is a gRPC status
+// with ErrorInfo and BadRequest details naming the processor.
+func (p *Pipeline) Process(ctx context.Context, msg *pb.Message) ([]*pb.Message, error) {
+	messages := []*pb.Message{msg}
+	for _, processor := range p.chain(msg.Room) {
+		var next []*pb.Message
+		for _, m := range messages {
+			out, err := processor.Process(ctx, m)
+			if err != nil {
+				return nil, rejectionStatus(ctx, processor.Name(), msg.Room, err)
+			}
+			next = append(next, out...)
+		}
+
+		if messages = next; len(messages) == 0 {
+			break
+		}
+	}
+
+	return messages, nil
+}
+
+func rejectionStatus(ctx context.Context, processor, room string, err error) error {
+	var rejection *Rejection
+	switch s, ok := status.FromError(err); {
+	case errors.As(err, &rejection):
+	case ok:
+		rejection = &Rejection{Code: s.Code(), Reason: "REJECTED", Message: s.Message()}
+	default:
+		// Внутренние ошибки обработчика не показываем отправителю
+		logging.FromContext(ctx).With("processor", processor, "error", err).Error("message processor failed")
+		rejection = &Rejection{Code: codes.Internal, Reason: "PROCESSOR_FAILED", Message: "message could not be processed"}
+	}
+
+	s, detailsErr := status.New(rejection.Code, rejection.Message).WithDetails(
+		&errdetails.ErrorInfo{
+			Reason:   rejection.Reason,
+			Domain:   Domain,
+			Metadata: map[string]string{"processor": processor, "room": room},
+		},
+		&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{
+			{Field: "body", Description: rejection.Message},
+		}},
+	)
+	if detailsErr != nil {
+		return status.Error(rejection.Code, rejection.Message)
+	}
+
+	return s.Err()
+}
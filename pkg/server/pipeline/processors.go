@@ -0,0 +1,105 @@
+package pipeline
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/proto"
+	pb "grpc-streaming/streaming/grpc"
+)
+
+// Length rejects bodies shorter than minRunes or longer than maxRunes
+// characters, surrounding spaces are not counted. 0 disables a bound.
+func Length(minRunes, maxRunes int) Processor {
+	return Func("length", func(_ context.Context, msg *pb.Message) ([]*pb.Message, error) {
+		n := utf8.RuneCountInString(strings.TrimSpace(msg.Body))
+		if minRunes > 0 && n < minRunes {
+			return nil, Reject("MESSAGE_TOO_SHORT", "message is shorter than %d characters", minRunes)
+		}
+		if maxRunes > 0 && n > maxRunes {
+			return nil, Reject("MESSAGE_TOO_LONG", "message is longer than %d characters", maxRunes)
+		}
+
+		return []*pb.Message{msg}, nil
+	})
+}
+
+// Profanity looks for the words, case-insensitive and as whole words. With
+// mask they are replaced by asterisks, otherwise the message is rejected.
+func Profanity(words []string, mask bool) Processor {
+	quoted := make([]string, 0, len(words))
+	for _, word := range words {
+		if word = strings.TrimSpace(word); word != "" {
+			quoted = append(quoted, regexp.QuoteMeta(word))
+		}
+	}
+	if len(quoted) == 0 {
+		return Func("profanity", func(_ context.Context, msg *pb.Message) ([]*pb.Message, error) {
+			return []*pb.Message{msg}, nil
+		})
+	}
+	re := regexp.MustCompile(`(?i)\b(` + strings.Join(quoted, "|") + `)\b`)
+
+	return Func("profanity", func(_ context.Context, msg *pb.Message) ([]*pb.Message, error) {
+		if !re.MatchString(msg.Body) {
+			return []*pb.Message{msg}, nil
+		}
+		if !mask {
+			return nil, Reject("PROFANITY", "message contains a blocked word")
+		}
+
+		msg.Body = re.ReplaceAllStringFunc(msg.Body, func(word string) string {
+			return strings.Repeat("*", utf8.RuneCountInString(word))
+		})
+		return []*pb.Message{msg}, nil
+	})
+}
+
+var linkPattern = regexp.MustCompile(`https?://[^\s<>"]+`)
+
+// Links stores the http and https links of the body in the "links" metadata
+// entry, separated by spaces.
+func Links() Processor {
+	return Func("links", func(_ context.Context, msg *pb.Message) ([]*pb.Message, error) {
+		links := linkPattern.FindAllString(msg.Body, -1)
+		if len(links) == 0 {
+			return []*pb.Message{msg}, nil
+		}
+
+		for i, link := range links {
+			// Знаки препинания в конце предложения не часть ссылки
+			links[i] = strings.TrimRight(link, ".,;:!?)")
+		}
+		if msg.Metadata == nil {
+			msg.Metadata = make(map[string]string)
+		}
+		msg.Metadata["links"] = strings.Join(links, " ")
+
+		return []*pb.Message{msg}, nil
+	})
+}
+
+// CopyTo fans a message out: it is published to its room and, as a copy, to
+// every one of rooms.
+func CopyTo(rooms ...string) Processor {
+	return Func("copy_to", func(_ context.Context, msg *pb.Message) ([]*pb.Message, error) {
+		out := []*pb.Message{msg}
+		for _, room := range rooms {
+			if room == msg.Room {
+				continue
+			}
+
+			clone := proto.Clone(msg).(*pb.Message)
+			clone.Room = room
+			if clone.Metadata == nil {
+				clone.Metadata = make(map[string]string)
+			}
+			clone.Metadata["copied_from"] = msg.Room
+			out = append(out, clone)
+		}
+
+		return out, nil
+	})
+}
@@ -0,0 +1,205 @@
+// Package server embeds the chat server into other processes: it builds the
+// gRPC server with the Chat service, the room hub and the message history,
+// and lets the host customize the streams through hooks.
+package server
+
+import (
+	"context"
+	"grpc-streaming/internal/server/history"
+	"grpc-streaming/internal/server/hub"
+	"grpc-streaming/internal/server/interceptors"
+	"net"
+	"net/http"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionalphapb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	pb "grpc-streaming/streaming/grpc"
+)
+
+// Hub routes the messages between the streams of a room, see Server.Hub.
+type Hub = hub.Hub
+
+// HubStats are the subscriber and queue counters of a Hub.
+type HubStats = hub.Stats
+
+// Client identifies the stream behind a subscriber.
+type Client = hub.Client
+
+// Subscriber is a stream subscribed to a room of a Hub.
+type Subscriber = hub.Subscriber
+
+// SubscriberInfo describes a Subscriber for listings.
+type SubscriberInfo = hub.SubscriberInfo
+
+// History keeps the last messages of every room, see Server.History.
+type History = history.Store
+
+// HistoryEntry is a message kept by History.
+type HistoryEntry = history.Entry
+
+// Server is a chat server. Additional services, e.g. health or reflection,
+// are registered on GRPCServer before Serve.
+type Server struct {
+	grpc     *grpc.Server
+	listener net.Listener
+	hub      *hub.Hub
+	history  *history.Store
+	gateway  *gateway
+}
+
+// New builds the server and, unless WithListener is given, listens on the
+// configured address.
+func New(opts ...Option) (*Server, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+
+	var accessibleRoles []string
+	if o.auth {
+		accessibleRoles = []string{"user", "admin"}
+	}
+
+	auditor := interceptors.NewAuditServerInterceptor(o.audit)
+	observer := interceptors.Observer(auditor)
+	if o.observer != nil {
+		observer = interceptors.MultiObserver(o.observer, auditor)
+	}
+
+	auth := interceptors.NewAuthServerInterceptor(accessibleRoles).
+		WithPublicMethods(healthpb.Health_Check_FullMethodName, healthpb.Health_Watch_FullMethodName).
+		WithMethodRoles(reflectionpb.ServerReflection_ServerReflectionInfo_FullMethodName, "admin").
+		WithMethodRoles(reflectionalphapb.ServerReflection_ServerReflectionInfo_FullMethodName, "admin").
+		WithObserver(observer)
+	for _, method := range pb.Admin_ServiceDesc.Methods {
+		auth.WithMethodRoles("/"+pb.Admin_ServiceDesc.ServiceName+"/"+method.MethodName, "admin")
+	}
+	for _, stream := range pb.Admin_ServiceDesc.Streams {
+		auth.WithMethodRoles("/"+pb.Admin_ServiceDesc.ServiceName+"/"+stream.StreamName, "admin")
+	}
+	if o.tokens != nil {
+		auth.WithTokens(o.tokens)
+	}
+
+	requestID := interceptors.NewRequestIDServerInterceptor()
+	serverOptions := append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(append([]grpc.UnaryServerInterceptor{requestID.Unary(), auth.Unary()}, o.unaryInterceptors...)...),
+		grpc.ChainStreamInterceptor(append([]grpc.StreamServerInterceptor{requestID.Stream(), auth.Stream(), auditor.Stream()}, o.streamInterceptors...)...),
+	}, o.serverOptions...)
+	if o.creds != nil {
+		serverOptions = append(serverOptions, grpc.Creds(o.creds))
+	}
+
+	lis := o.listener
+	if lis == nil {
+		var err error
+		if lis, err = net.Listen("tcp", o.address); err != nil {
+			return nil, err
+		}
+	}
+
+	s := &Server{
+		grpc:     grpc.NewServer(serverOptions...),
+		listener: lis,
+		hub:      hub.New(o.queueSize, o.policy),
+		history:  history.New(o.historySize),
+	}
+
+	chat := &chatService{
+		hub:               s.hub,
+		history:           s.history,
+		audit:             o.audit,
+		heartbeatInterval: o.heartbeatInterval,
+		idleTimeout:       o.idleTimeout,
+		listenTimeout:     o.listenTimeout,
+		onJoin:            o.onJoin,
+		onMessage:         o.onMessage,
+		onLeave:           o.onLeave,
+		pipeline:          o.pipeline,
+		feed:              o.feed,
+	}
+	pb.RegisterChatServer(s.grpc, chat)
+	s.gateway = &gateway{
+		chat:     chat,
+		auth:     auth,
+		limiter:  o.gatewayLimiter,
+		streams:  o.gatewayStreams,
+		upgrader: websocket.Upgrader{CheckOrigin: checkOrigin(o.webSocketOrigins)},
+		quit:     make(chan struct{}),
+	}
+
+	return s, nil
+}
+
+// GRPCServer exposes the underlying server to register more services.
+func (s *Server) GRPCServer() *grpc.Server {
+	return s.grpc
+}
+
+// Addr is the address the server listens on.
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Hub routes the messages between the streams of a room.
+func (s *Server) Hub() *Hub {
+	return s.hub
+}
+
+// History is the in-memory message history of the rooms.
+func (s *Server) History() *History {
+	return s.history
+}
+
+// Gateway serves the Chat service to HTTP clients with the auth of the gRPC
+// server and protojson bodies, the host serves it on a listener of its own:
+//
+//	POST /v1/rooms/{room}/messages  publishes the body of the posted Message
+//	GET  /v1/rooms/{room}/messages  streams the room as Server-Sent Events for
+//	                                Accept: text/event-stream, NDJSON otherwise
+//	GET  /v1/rooms/{room}/ws        joins the room over a WebSocket, see
+//	                                WithWebSocketOrigins
+//
+// Errors are answered with the HTTP code of the gRPC code and the status as
+// JSON. The gRPC interceptors do not apply, see WithGatewayLimiter.
+func (s *Server) Gateway() http.Handler {
+	return s.gateway.handler()
+}
+
+// Serve accepts connections until Shutdown or Stop.
+func (s *Server) Serve() error {
+	return s.grpc.Serve(s.listener)
+}
+
+// Shutdown stops accepting connections and waits for the streams to finish,
+// once ctx is done the remaining streams are closed. It reports ctx.Err() in
+// that case. The gateway streams are closed right away.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.gateway.close()
+
+	stopped := make(chan struct{})
+	go func() {
+		s.grpc.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.grpc.Stop()
+		return ctx.Err()
+	}
+}
+
+// Stop closes every connection and stream immediately.
+func (s *Server) Stop() {
+	s.gateway.close()
+	s.grpc.Stop()
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestNewRejectsNonPositiveDurations(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  Option
+	}{
+		{"zero heartbeat interval", WithHeartbeat(0, time.Minute)},
+		{"negative heartbeat interval", WithHeartbeat(-time.Second, time.Minute)},
+		{"zero idle timeout", WithHeartbeat(time.Second, 0)},
+		{"negative idle timeout", WithHeartbeat(time.Second, -time.Minute)},
+		{"zero listen timeout", WithGRPCWebListenTimeout(0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(WithListener(bufconn.Listen(1<<20)), tt.opt)
+			if err == nil {
+				s.Stop()
+				t.Fatal("New accepted the options")
+			}
+		})
+	}
+
+	s, err := New(WithListener(bufconn.Listen(1<<20)), WithHeartbeat(time.Second, 3*time.Second))
+	if err != nil {
+		t.Fatalf("positive durations: %v", err)
+	}
+	s.Stop()
+}
@@ -0,0 +1,213 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"grpc-streaming/internal/logging"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	pb "grpc-streaming/streaming/grpc"
+)
+
+// writeTimeout bounds a single frame write to a WebSocket client.
+const writeTimeout = 10 * time.Second
+
+// checkOrigin allows the cross-origin browser pages of origins, see
+// allowOrigin. Without origins only same-origin pages may connect.
+func checkOrigin(origins []string) func(*http.Request) bool {
+	if len(origins) == 0 {
+		// Проверка того же origin по умолчанию в gorilla/websocket
+		return nil
+	}
+
+	allowed := allowOrigin(origins)
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		return origin == "" || allowed(origin)
+	}
+}
+
+// allowOrigin matches the Origin of browser pages against origins, "*"
+// allows every page.
+func allowOrigin(origins []string) func(origin string) bool {
+	allowed := make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		allowed[origin] = true
+	}
+
+	return func(origin string) bool {
+		return allowed["*"] || allowed[origin]
+	}
+}
+
+// webSocket makes the connection a ChatStream participant of the room: text
+// frames carry protojson Messages both ways, the server pings every heartbeat
+// interval and closes connections silent, pongs included, for the idle
+// timeout. Browsers that cannot set the authorization header pass the token
+// as the access_token query parameter.
+func (g *gateway) webSocket(w http.ResponseWriter, r *http.Request) {
+	room := r.PathValue("room")
+	if token := r.URL.Query().Get("access_token"); token != "" && r.Header.Get("Authorization") == "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	ctx, ok := g.authorize(w, r, room)
+	if !ok {
+		return
+	}
+
+	// Upgrade сам отвечает клиенту при ошибке
+	conn, err := g.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logging.FromContext(ctx).With("error", err).Warn("websocket upgrade failed")
+		return
+	}
+	defer conn.Close()
+
+	// cancel отпускает receive, если соединение закрывает сервер
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	session := newSession(ctx, room)
+	sub, release, err := g.join(ctx, session)
+	if err != nil {
+		closeWebSocket(conn, room, err)
+		return
+	}
+	defer func() {
+		g.chat.leave(ctx, session, sub, err)
+		release()
+		closeWebSocket(conn, room, err)
+		logging.FromContext(ctx).With("error", err).Info("websocket closed")
+	}()
+
+	conn.SetReadLimit(maxGatewayBodySize)
+	_ = conn.SetReadDeadline(time.Now().Add(g.chat.idleTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(g.chat.idleTimeout))
+	})
+
+	// Как и в ChatStream, чтение идет в отдельной горутине, а пишет только эта
+	recvErr := make(chan error, 1)
+	replies := make(chan *pb.Message, 16)
+	go func() {
+		recvErr <- g.receive(ctx, conn, session, replies)
+	}()
+
+	ping := time.NewTicker(g.chat.heartbeatInterval)
+	defer ping.Stop()
+
+	for err == nil {
+		select {
+		case msg := <-sub.Messages():
+			err = writeFrame(conn, msg)
+		case msg := <-replies:
+			err = writeFrame(conn, msg)
+		case <-ping.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout))
+		case <-sub.Done():
+			if err = sub.Err(); err == nil {
+				err = status.Error(codes.Aborted, "stream closed by the server")
+			}
+		case <-g.quit:
+			err = status.Error(codes.Unavailable, "server is shutting down")
+		case err = <-recvErr:
+			if err == nil {
+				return
+			}
+		}
+	}
+}
+
+// receive handles the frames of the client until it closes the connection.
+func (g *gateway) receive(ctx context.Context, conn *websocket.Conn, session Session, replies chan<- *pb.Message) error {
+	for {
+		_, data, err := conn.ReadMessage()
+		if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+			return nil
+		}
+		if err != nil {
+			return status.Errorf(codes.Unavailable, "websocket: %v", err)
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(g.chat.idleTimeout))
+
+		msg := &pb.Message{}
+		if err = protojson.Unmarshal(data, msg); err != nil {
+			return status.Errorf(codes.InvalidArgument, "frame is not a Message: %v", err)
+		}
+		if msg.Type == pb.Message_HEARTBEAT {
+			continue
+		}
+
+		if g.limiter != nil {
+			if err = g.limiter.Allow(ctx, pb.Chat_ChatStream_FullMethodName, msg); err != nil {
+				return err
+			}
+		}
+
+		rejection, err := g.chat.handle(ctx, session, msg.Body)
+		if err != nil {
+			return err
+		}
+		if rejection != nil {
+			select {
+			case replies <- rejection:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+func writeFrame(conn *websocket.Conn, msg *pb.Message) error {
+	data, err := marshalOptions.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	_ = conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// closeWebSocket sends the status that ended the connection as a last ERROR
+// message and the close frame, a nil err is a normal closure.
+func closeWebSocket(conn *websocket.Conn, room string, err error) {
+	code, reason := websocket.CloseNormalClosure, ""
+	if err != nil {
+		st := status.Convert(err)
+		if errors.Is(err, context.Canceled) {
+			st = status.FromContextError(err)
+		}
+		_ = writeFrame(conn, rejectionMessage(room, st.Err()))
+
+		code, reason = closeCode(st.Code()), st.Message()
+	}
+
+	// Причина в close-фрейме ограничена 123 байтами
+	for len(reason) > 123 {
+		_, size := utf8.DecodeLastRuneInString(reason)
+		reason = reason[:len(reason)-size]
+	}
+	_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(writeTimeout))
+}
+
+func closeCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return websocket.CloseNormalClosure
+	case codes.Unavailable:
+		return websocket.CloseGoingAway
+	case codes.ResourceExhausted:
+		return websocket.CloseTryAgainLater
+	case codes.InvalidArgument, codes.PermissionDenied, codes.Unauthenticated, codes.FailedPrecondition:
+		return websocket.ClosePolicyViolation
+	}
+
+	return websocket.CloseInternalServerErr
+}
@@ -20,14 +20,23 @@ const _ = grpc.SupportPackageIsVersion7
 
 const (
 	Chat_ChatStream_FullMethodName = "/streaming.Chat/ChatStream"
+	Chat_Who_FullMethodName        = "/streaming.Chat/Who"
+	Chat_Feed_FullMethodName       = "/streaming.Chat/Feed"
+	Chat_Rooms_FullMethodName      = "/streaming.Chat/Rooms"
+	Chat_History_FullMethodName    = "/streaming.Chat/History"
 )
 
 // ChatClient is the client API for Chat service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type ChatClient interface {
-	// Биде·ре·кциональный стриминг (двусторонний поток)
+	// Двунаправленный стриминг (двусторонний поток)
 	ChatStream(ctx context.Context, opts ...grpc.CallOption) (Chat_ChatStreamClient, error)
+	Who(ctx context.Context, in *WhoRequest, opts ...grpc.CallOption) (*WhoResponse, error)
+	// Вызывает настроенный на сервере REST-сервис и отдает элементы ответа по мере чтения
+	Feed(ctx context.Context, in *FeedRequest, opts ...grpc.CallOption) (Chat_FeedClient, error)
+	Rooms(ctx context.Context, in *RoomsRequest, opts ...grpc.CallOption) (*RoomsResponse, error)
+	History(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (*HistoryResponse, error)
 }
 
 type chatClient struct {
@@ -69,12 +78,76 @@ func (x *chatChatStreamClient) Recv() (*Message, error) {
 	return m, nil
 }
 
+func (c *chatClient) Who(ctx context.Context, in *WhoRequest, opts ...grpc.CallOption) (*WhoResponse, error) {
+	out := new(WhoResponse)
+	err := c.cc.Invoke(ctx, Chat_Who_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatClient) Feed(ctx context.Context, in *FeedRequest, opts ...grpc.CallOption) (Chat_FeedClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Chat_ServiceDesc.Streams[1], Chat_Feed_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &chatFeedClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Chat_FeedClient interface {
+	Recv() (*Message, error)
+	grpc.ClientStream
+}
+
+type chatFeedClient struct {
+	grpc.ClientStream
+}
+
+func (x *chatFeedClient) Recv() (*Message, error) {
+	m := new(Message)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *chatClient) Rooms(ctx context.Context, in *RoomsRequest, opts ...grpc.CallOption) (*RoomsResponse, error) {
+	out := new(RoomsResponse)
+	err := c.cc.Invoke(ctx, Chat_Rooms_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatClient) History(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (*HistoryResponse, error) {
+	out := new(HistoryResponse)
+	err := c.cc.Invoke(ctx, Chat_History_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ChatServer is the server API for Chat service.
 // All implementations must embed UnimplementedChatServer
 // for forward compatibility
 type ChatServer interface {
-	// Биде·ре·кциональный стриминг (двусторонний поток)
+	// Двунаправленный стриминг (двусторонний поток)
 	ChatStream(Chat_ChatStreamServer) error
+	Who(context.Context, *WhoRequest) (*WhoResponse, error)
+	// Вызывает настроенный на сервере REST-сервис и отдает элементы ответа по мере чтения
+	Feed(*FeedRequest, Chat_FeedServer) error
+	Rooms(context.Context, *RoomsRequest) (*RoomsResponse, error)
+	History(context.Context, *HistoryRequest) (*HistoryResponse, error)
 	mustEmbedUnimplementedChatServer()
 }
 
@@ -85,6 +158,18 @@ type UnimplementedChatServer struct {
 func (UnimplementedChatServer) ChatStream(Chat_ChatStreamServer) error {
 	return status.Errorf(codes.Unimplemented, "method ChatStream not implemented")
 }
+func (UnimplementedChatServer) Who(context.Context, *WhoRequest) (*WhoResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Who not implemented")
+}
+func (UnimplementedChatServer) Feed(*FeedRequest, Chat_FeedServer) error {
+	return status.Errorf(codes.Unimplemented, "method Feed not implemented")
+}
+func (UnimplementedChatServer) Rooms(context.Context, *RoomsRequest) (*RoomsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Rooms not implemented")
+}
+func (UnimplementedChatServer) History(context.Context, *HistoryRequest) (*HistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method History not implemented")
+}
 func (UnimplementedChatServer) mustEmbedUnimplementedChatServer() {}
 
 // UnsafeChatServer may be embedded to opt out of forward compatibility for this service.
@@ -124,13 +209,101 @@ func (x *chatChatStreamServer) Recv() (*Message, error) {
 	return m, nil
 }
 
+func _Chat_Who_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WhoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServer).Who(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Chat_Who_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServer).Who(ctx, req.(*WhoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Chat_Feed_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(FeedRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ChatServer).Feed(m, &chatFeedServer{stream})
+}
+
+type Chat_FeedServer interface {
+	Send(*Message) error
+	grpc.ServerStream
+}
+
+type chatFeedServer struct {
+	grpc.ServerStream
+}
+
+func (x *chatFeedServer) Send(m *Message) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Chat_Rooms_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RoomsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServer).Rooms(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Chat_Rooms_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServer).Rooms(ctx, req.(*RoomsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Chat_History_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServer).History(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Chat_History_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServer).History(ctx, req.(*HistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Chat_ServiceDesc is the grpc.ServiceDesc for Chat service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
 var Chat_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "streaming.Chat",
 	HandlerType: (*ChatServer)(nil),
-	Methods:     []grpc.MethodDesc{},
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Who",
+			Handler:    _Chat_Who_Handler,
+		},
+		{
+			MethodName: "Rooms",
+			Handler:    _Chat_Rooms_Handler,
+		},
+		{
+			MethodName: "History",
+			Handler:    _Chat_History_Handler,
+		},
+	},
 	Streams: []grpc.StreamDesc{
 		{
 			StreamName:    "ChatStream",
@@ -138,6 +311,11 @@ var Chat_ServiceDesc = grpc.ServiceDesc{
 			ServerStreams: true,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "Feed",
+			Handler:       _Chat_Feed_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "streaming/streaming.proto",
 }